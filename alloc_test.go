@@ -0,0 +1,33 @@
+//go:build !race
+// +build !race
+
+// The race detector randomly drops the items put in a sync.Pool.
+
+package hlog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnabledAllocs(t *testing.T) {
+	for _, f := range benchFormatters() {
+		logger := benchLogger(f.formatter(), InfoLevel)
+		entry := logger.WithFields(benchFields)
+		typed := logger.With(Str("user", "alice"), Int("request", 42), Bool("ok", true))
+		t.Run(f.name, func(t *testing.T) {
+			assert.Zero(t, testing.AllocsPerRun(100, func() { logger.Info("request served") }))
+			assert.Zero(t, testing.AllocsPerRun(100, func() { entry.Info("request served") }))
+			assert.Zero(t, testing.AllocsPerRun(100, func() { typed.Info("request served") }))
+		})
+	}
+}
+
+func TestDisabledAllocs(t *testing.T) {
+	logger := benchLogger(&JSONFormatter{}, ErrorLevel)
+	entry := logger.WithFields(benchFields)
+	assert.Zero(t, testing.AllocsPerRun(100, func() { logger.Info("request served") }))
+	assert.Zero(t, testing.AllocsPerRun(100, func() { logger.Infof("request %d served", 42) }))
+	assert.Zero(t, testing.AllocsPerRun(100, func() { entry.Debug("request served") }))
+}
@@ -0,0 +1,255 @@
+package hlog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy tells what to do with an entry when a bounded queue is full.
+type OverflowPolicy uint8
+
+const (
+	// OverflowBlock waits for room in the queue.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the entry being queued.
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest queued entry to make room.
+	OverflowDropOldest
+)
+
+// String returns the name of the policy.
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropNewest:
+		return "drop_newest"
+	case OverflowDropOldest:
+		return "drop_oldest"
+	}
+	return fmt.Sprintf("OverflowPolicy(%d)", uint8(p))
+}
+
+// AsyncWriterOptions configures an AsyncWriter.
+type AsyncWriterOptions struct {
+	// Size is the number of entries the queue holds, 1024 by default.
+	Size int
+	// Overflow is the policy applied when the queue is full.
+	Overflow OverflowPolicy
+	// ExitTimeout bounds how long the exit handlers wait for the queue to
+	// drain, 5 seconds by default.
+	ExitTimeout time.Duration
+}
+
+// AsyncWriter is an io.Writer queueing writes in a bounded ring buffer that
+// a goroutine drains into the wrapped writer, so that logging goroutines do
+// not wait on a slow output:
+//
+//	logger.SetOutput(hlog.NewAsyncWriter(file, hlog.AsyncWriterOptions{
+//		Overflow: hlog.OverflowDropOldest,
+//	}))
+//
+// The queue is drained when the program exits through Exit or a Fatal
+// entry. Once closed, writes go straight to the wrapped writer.
+type AsyncWriter struct {
+	out  io.Writer
+	opts AsyncWriterOptions
+
+	mu      sync.Mutex
+	buf     [][]byte
+	head, n int
+	busy    bool
+	closing bool
+	closed  bool
+	// progress is closed and replaced whenever entries leave the queue
+	progress chan struct{}
+
+	wake    chan struct{}
+	done    chan struct{}
+	dropped uint64
+}
+
+// asyncWriters are the AsyncWriters not closed yet, for the exit handler
+// to drain them.
+var (
+	asyncWritersMu   sync.Mutex
+	asyncWriters     = make(map[*AsyncWriter]struct{})
+	asyncWritersOnce sync.Once
+)
+
+// closeAsyncWriters is the exit handler closing the AsyncWriters.
+func closeAsyncWriters() {
+	asyncWritersMu.Lock()
+	writers := make([]*AsyncWriter, 0, len(asyncWriters))
+	for w := range asyncWriters {
+		writers = append(writers, w)
+	}
+	asyncWritersMu.Unlock()
+	for _, w := range writers {
+		ctx, cancel := context.WithTimeout(context.Background(), w.opts.ExitTimeout)
+		if err := w.Close(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to flush log output, %v\n", err)
+		}
+		cancel()
+	}
+}
+
+// NewAsyncWriter returns an AsyncWriter writing to out. It is closed by the
+// exit handlers until Close is called.
+func NewAsyncWriter(out io.Writer, opts AsyncWriterOptions) *AsyncWriter {
+	if opts.Size <= 0 {
+		opts.Size = 1024
+	}
+	if opts.ExitTimeout <= 0 {
+		opts.ExitTimeout = 5 * time.Second
+	}
+	w := &AsyncWriter{
+		out:      out,
+		opts:     opts,
+		buf:      make([][]byte, opts.Size),
+		progress: make(chan struct{}),
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	asyncWritersOnce.Do(func() { RegisterExitHandler(closeAsyncWriters) })
+	asyncWritersMu.Lock()
+	asyncWriters[w] = struct{}{}
+	asyncWritersMu.Unlock()
+	return w
+}
+
+// Write queues a copy of p. It never fails, write errors of the wrapped
+// writer are reported on stderr.
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	for !w.closed && w.n == len(w.buf) {
+		switch w.opts.Overflow {
+		case OverflowDropNewest:
+			w.mu.Unlock()
+			atomic.AddUint64(&w.dropped, 1)
+			return len(p), nil
+		case OverflowDropOldest:
+			w.buf[w.head] = nil
+			w.head = (w.head + 1) % len(w.buf)
+			w.n--
+			atomic.AddUint64(&w.dropped, 1)
+		default:
+			progress := w.progress
+			w.mu.Unlock()
+			<-progress
+			w.mu.Lock()
+		}
+	}
+	if w.closed {
+		w.mu.Unlock()
+		return w.out.Write(p)
+	}
+	w.buf[(w.head+w.n)%len(w.buf)] = append([]byte(nil), p...)
+	w.n++
+	w.mu.Unlock()
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// Dropped returns the number of entries dropped because the queue was full.
+func (w *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Flush waits until the queued entries are written, or ctx is done.
+func (w *AsyncWriter) Flush(ctx context.Context) error {
+	for {
+		w.mu.Lock()
+		if w.closed || (w.n == 0 && !w.busy) {
+			w.mu.Unlock()
+			return nil
+		}
+		progress := w.progress
+		w.mu.Unlock()
+
+		select {
+		case <-progress:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close writes the queued entries and stops the goroutine draining the
+// queue, unless ctx is done first. The wrapped writer is not closed.
+func (w *AsyncWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	w.closing = true
+	w.mu.Unlock()
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+
+	select {
+	case <-w.done:
+		asyncWritersMu.Lock()
+		delete(asyncWriters, w)
+		asyncWritersMu.Unlock()
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	var batch [][]byte
+	for {
+		w.mu.Lock()
+		for w.n == 0 {
+			if w.closing {
+				w.closed = true
+				w.signal()
+				w.mu.Unlock()
+				return
+			}
+			w.mu.Unlock()
+			<-w.wake
+			w.mu.Lock()
+		}
+		batch = batch[:0]
+		for ; w.n > 0; w.n-- {
+			batch = append(batch, w.buf[w.head])
+			w.buf[w.head] = nil
+			w.head = (w.head + 1) % len(w.buf)
+		}
+		w.busy = true
+		w.signal()
+		w.mu.Unlock()
+
+		for i, b := range batch {
+			if _, err := w.out.Write(b); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+			}
+			batch[i] = nil
+		}
+
+		w.mu.Lock()
+		w.busy = false
+		w.signal()
+		w.mu.Unlock()
+	}
+}
+
+// signal wakes up the goroutines waiting for progress. It must be called
+// with w.mu held.
+func (w *AsyncWriter) signal() {
+	close(w.progress)
+	w.progress = make(chan struct{})
+}
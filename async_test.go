@@ -0,0 +1,131 @@
+package hlog
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedWriter blocks writes until its gate is opened.
+type gatedWriter struct {
+	gate chan struct{}
+	mu   sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gatedWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncWriter(t *testing.T) {
+	out := &gatedWriter{gate: make(chan struct{})}
+	close(out.gate)
+	w := NewAsyncWriter(out, AsyncWriterOptions{})
+
+	logger := New()
+	logger.Formatter = &TextFormatter{DisableTimestamp: true}
+	logger.SetOutput(w)
+	for i := 0; i < 100; i++ {
+		logger.Info("async")
+	}
+	require.NoError(t, w.Flush(context.Background()))
+	assert.Equal(t, 100, strings.Count(out.String(), "msg=async"))
+
+	require.NoError(t, w.Close(context.Background()))
+	logger.Info("closed")
+	assert.Contains(t, out.String(), "msg=closed")
+	assert.Equal(t, uint64(0), w.Dropped())
+}
+
+func TestAsyncWriterOverflow(t *testing.T) {
+	for _, policy := range []OverflowPolicy{OverflowDropNewest, OverflowDropOldest} {
+		t.Run(policy.String(), func(t *testing.T) {
+			out := &gatedWriter{gate: make(chan struct{})}
+			w := NewAsyncWriter(out, AsyncWriterOptions{Size: 2, Overflow: policy})
+
+			w.Write([]byte("0\n"))
+			// wait for the first write to be taken off the queue
+			assert.Eventually(t, func() bool {
+				w.mu.Lock()
+				defer w.mu.Unlock()
+				return w.busy
+			}, time.Second, time.Millisecond)
+			for _, line := range []string{"1\n", "2\n", "3\n"} {
+				w.Write([]byte(line))
+			}
+			close(out.gate)
+			require.NoError(t, w.Flush(context.Background()))
+
+			assert.Equal(t, uint64(1), w.Dropped())
+			if policy == OverflowDropNewest {
+				assert.Equal(t, "0\n1\n2\n", out.String())
+			} else {
+				assert.Equal(t, "0\n2\n3\n", out.String())
+			}
+		})
+	}
+}
+
+func TestAsyncWriterBlock(t *testing.T) {
+	out := &gatedWriter{gate: make(chan struct{})}
+	w := NewAsyncWriter(out, AsyncWriterOptions{Size: 1})
+
+	w.Write([]byte("0\n"))
+	w.Write([]byte("1\n"))
+	written := make(chan struct{})
+	go func() {
+		w.Write([]byte("2\n"))
+		close(written)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, w.Flush(ctx))
+
+	close(out.gate)
+	<-written
+	require.NoError(t, w.Close(context.Background()))
+	assert.Equal(t, "0\n1\n2\n", out.String())
+	assert.Equal(t, uint64(0), w.Dropped())
+}
+
+func TestAsyncWriterExitHandler(t *testing.T) {
+	out := &gatedWriter{gate: make(chan struct{})}
+	close(out.gate)
+	w := NewAsyncWriter(out, AsyncWriterOptions{})
+	registered := len(handlers)
+	closed := NewAsyncWriter(ioutil.Discard, AsyncWriterOptions{})
+	require.NoError(t, closed.Close(context.Background()))
+	assert.Equal(t, registered, len(handlers))
+
+	asyncWritersMu.Lock()
+	assert.Contains(t, asyncWriters, w)
+	assert.NotContains(t, asyncWriters, closed)
+	asyncWritersMu.Unlock()
+
+	_, err := w.Write([]byte("queued\n"))
+	require.NoError(t, err)
+	closeAsyncWriters()
+	assert.Equal(t, "queued\n", out.String())
+
+	// closed writers are released
+	asyncWritersMu.Lock()
+	assert.NotContains(t, asyncWriters, w)
+	asyncWritersMu.Unlock()
+}
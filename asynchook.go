@@ -0,0 +1,284 @@
+package hlog
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// AsyncHookOptions configures the hooks returned by AsyncHook.
+type AsyncHookOptions struct {
+	// Size is the number of entries the queue holds, 1024 by default.
+	Size int
+	// Workers is the number of goroutines firing the hook, 1 by default.
+	Workers int
+	// Overflow is the policy applied when the queue is full.
+	Overflow OverflowPolicy
+}
+
+// AsyncHookWrapper fires a hook from a bounded queue, see AsyncHook.
+type AsyncHookWrapper struct {
+	hook Hook
+	opts AsyncHookOptions
+
+	queue   chan *Entry
+	workers sync.WaitGroup
+
+	// closeMu is only held to queue without blocking, and to close
+	closeMu sync.RWMutex
+	closed  bool
+	closing chan struct{}
+
+	mu      sync.Mutex
+	pending int
+	// progress is closed and replaced whenever an entry leaves the queue
+	progress chan struct{}
+
+	dropped uint64
+}
+
+// AsyncHook wraps a hook so that Fire only queues a copy of the entry, the
+// hook being fired by worker goroutines. Errors of the hook are passed to
+// the ErrorHandler of the logger of the entry.
+//
+// Flush and Close should be called before the program exits to not lose
+// the queued entries. Once closed, the hook is fired synchronously.
+func AsyncHook(hook Hook, opts AsyncHookOptions) *AsyncHookWrapper {
+	if opts.Size <= 0 {
+		opts.Size = 1024
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	h := &AsyncHookWrapper{
+		hook:     hook,
+		opts:     opts,
+		queue:    make(chan *Entry, opts.Size),
+		closing:  make(chan struct{}),
+		progress: make(chan struct{}),
+	}
+	h.workers.Add(opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		go h.work()
+	}
+	return h
+}
+
+// Levels returns the levels of the wrapped hook.
+func (h *AsyncHookWrapper) Levels() []Level {
+	return h.hook.Levels()
+}
+
+// Fire queues a copy of the entry.
+func (h *AsyncHookWrapper) Fire(entry *Entry) error {
+	var queued *Entry
+	for {
+		h.mu.Lock()
+		progress := h.progress
+		h.mu.Unlock()
+
+		h.closeMu.RLock()
+		if h.closed {
+			h.closeMu.RUnlock()
+			if queued != nil {
+				h.add(-1)
+			}
+			return h.hook.Fire(entry)
+		}
+		if queued == nil {
+			queued = entry.deepCopy()
+			h.add(1)
+		}
+		ok := h.tryQueue(queued)
+		h.closeMu.RUnlock()
+		if ok {
+			return nil
+		}
+		// Wait for room without holding closeMu, so that a stuck hook
+		// doesn't keep Close from returning.
+		select {
+		case <-progress:
+		case <-h.closing:
+		}
+	}
+}
+
+// tryQueue queues the entry without blocking, applying the overflow policy.
+// It reports false when the entry has to wait for room in the queue. It must
+// be called with closeMu read locked.
+func (h *AsyncHookWrapper) tryQueue(entry *Entry) bool {
+	for {
+		select {
+		case h.queue <- entry:
+			return true
+		default:
+		}
+		switch h.opts.Overflow {
+		case OverflowDropNewest:
+			atomic.AddUint64(&h.dropped, 1)
+			h.add(-1)
+			return true
+		case OverflowDropOldest:
+			select {
+			case <-h.queue:
+				atomic.AddUint64(&h.dropped, 1)
+				h.add(-1)
+			default:
+			}
+		default:
+			return false
+		}
+	}
+}
+
+// Dropped returns the number of entries dropped because the queue was full.
+func (h *AsyncHookWrapper) Dropped() uint64 {
+	return atomic.LoadUint64(&h.dropped)
+}
+
+// Flush waits until the queued entries are fired and then flushes the
+// wrapped hook, unless ctx is done first.
+func (h *AsyncHookWrapper) Flush(ctx context.Context) error {
+	for {
+		h.mu.Lock()
+		if h.pending == 0 {
+			h.mu.Unlock()
+			return flushTarget(ctx, h.hook)
+		}
+		progress := h.progress
+		h.mu.Unlock()
+
+		select {
+		case <-progress:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close fires the queued entries, stops the workers and closes the wrapped
+// hook if it is a Closer, unless ctx is done first.
+func (h *AsyncHookWrapper) Close(ctx context.Context) error {
+	h.closeMu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.closing)
+		close(h.queue)
+	}
+	h.closeMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return closeTarget(ctx, h.hook)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *AsyncHookWrapper) work() {
+	defer h.workers.Done()
+	for entry := range h.queue {
+		if err := h.hook.Fire(entry); err != nil {
+			var handler HookErrorHandler
+			if entry.Logger != nil {
+				entry.Logger.lock().Lock()
+				handler = entry.Logger.ErrorHandler
+				entry.Logger.lock().Unlock()
+			}
+			entry.handleHookErrors(handler, HookErrors{{Hook: h.hook, Err: err}})
+		}
+		h.add(-1)
+	}
+}
+
+// add changes the number of pending entries.
+func (h *AsyncHookWrapper) add(n int) {
+	h.mu.Lock()
+	h.pending += n
+	if n < 0 {
+		close(h.progress)
+		h.progress = make(chan struct{})
+	}
+	h.mu.Unlock()
+}
+
+// deepCopy returns a copy of the entry that shares no map or slice with it,
+// for it to be used once the entry was logged. Pointers in the fields are
+// still shared.
+func (entry *Entry) deepCopy() *Entry {
+	dup := &Entry{
+		Logger:  entry.Logger,
+		Data:    deepCopyValue(reflect.ValueOf(entry.Data)).Interface().(Fields),
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Message: entry.Message,
+		Context: entry.Context,
+		err:     entry.err,
+	}
+	if entry.Data == nil {
+		dup.Data = Fields{}
+	}
+	if entry.Caller != nil {
+		caller := *entry.Caller
+		dup.Caller = &caller
+	}
+	if len(entry.fields) > 0 {
+		dup.fields = make([]Field, len(entry.fields))
+		for i, f := range entry.fields {
+			if f.stringsValue != nil {
+				f.stringsValue = append([]string(nil), f.stringsValue...)
+			}
+			if f.interfaceValue != nil && f.kind == InterfaceField {
+				f.interfaceValue = deepCopyValue(reflect.ValueOf(f.interfaceValue)).Interface()
+			}
+			dup.fields[i] = f
+		}
+	}
+	return dup
+}
+
+// deepCopyValue copies maps and slices recursively.
+func deepCopyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := deepCopyValue(v.Elem())
+		out := reflect.New(v.Type()).Elem()
+		out.Set(c)
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), deepCopyValue(iter.Value()))
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		switch v.Type().Elem().Kind() {
+		case reflect.Interface, reflect.Map, reflect.Slice:
+		default:
+			reflect.Copy(out, v)
+			return out
+		}
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(deepCopyValue(v.Index(i)))
+		}
+		return out
+	}
+	return v
+}
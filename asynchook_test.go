@@ -0,0 +1,146 @@
+package hlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingHook records the entries it is fired with once its gate is open.
+type blockingHook struct {
+	gate    chan struct{}
+	mu      sync.Mutex
+	entries []*Entry
+	err     error
+}
+
+func (h *blockingHook) Levels() []Level {
+	return AllLevels
+}
+
+func (h *blockingHook) Fire(entry *Entry) error {
+	<-h.gate
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = append(h.entries, entry)
+	return h.err
+}
+
+func (h *blockingHook) messages() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var msgs []string
+	for _, entry := range h.entries {
+		msgs = append(msgs, entry.Message)
+	}
+	return msgs
+}
+
+func TestAsyncHook(t *testing.T) {
+	inner := &blockingHook{gate: make(chan struct{})}
+	hook := AsyncHook(inner, AsyncHookOptions{Workers: 2})
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.AddHook(hook)
+
+	nested := map[string]interface{}{"k": "before"}
+	logger.WithField("nested", nested).With(Strs("list", []string{"before"})).Info("copied")
+	nested["k"] = "after"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, hook.Flush(ctx))
+
+	close(inner.gate)
+	require.NoError(t, hook.Flush(context.Background()))
+	require.Len(t, inner.entries, 1)
+	assert.Equal(t, "before", inner.entries[0].Data["nested"].(map[string]interface{})["k"])
+	assert.Equal(t, []string{"before"}, inner.entries[0].Data["list"])
+
+	require.NoError(t, hook.Close(context.Background()))
+	logger.Info("closed")
+	assert.Equal(t, []string{"copied", "closed"}, inner.messages())
+}
+
+func TestAsyncHookOverflow(t *testing.T) {
+	for _, policy := range []OverflowPolicy{OverflowDropNewest, OverflowDropOldest} {
+		t.Run(policy.String(), func(t *testing.T) {
+			inner := &blockingHook{gate: make(chan struct{})}
+			hook := AsyncHook(inner, AsyncHookOptions{Size: 1, Overflow: policy})
+			logger := New()
+			logger.Out = &bytes.Buffer{}
+			logger.AddHook(hook)
+
+			logger.Info("0")
+			// wait for the worker to take the first entry
+			assert.Eventually(t, func() bool { return len(hook.queue) == 0 }, time.Second, time.Millisecond)
+			logger.Info("1")
+			logger.Info("2")
+			close(inner.gate)
+			require.NoError(t, hook.Close(context.Background()))
+
+			assert.Equal(t, uint64(1), hook.Dropped())
+			if policy == OverflowDropNewest {
+				assert.Equal(t, []string{"0", "1"}, inner.messages())
+			} else {
+				assert.Equal(t, []string{"0", "2"}, inner.messages())
+			}
+		})
+	}
+}
+
+func TestAsyncHookErrors(t *testing.T) {
+	inner := &blockingHook{gate: make(chan struct{}), err: errors.New("down")}
+	close(inner.gate)
+	hook := AsyncHook(inner, AsyncHookOptions{})
+
+	errs := make(chan error, 1)
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.SetErrorHandler(func(h Hook, entry *Entry, err error) {
+		assert.Same(t, inner, h)
+		errs <- err
+	})
+	logger.AddHook(hook)
+
+	logger.Info("fails")
+	assert.EqualError(t, <-errs, "down")
+	require.NoError(t, hook.Close(context.Background()))
+}
+
+func TestAsyncHookCloseStuck(t *testing.T) {
+	inner := &blockingHook{gate: make(chan struct{})}
+	defer close(inner.gate)
+	hook := AsyncHook(inner, AsyncHookOptions{Size: 1})
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.AddHook(hook)
+
+	logger.Info("0")
+	assert.Eventually(t, func() bool { return len(hook.queue) == 0 }, time.Second, time.Millisecond)
+	logger.Info("1")
+	go logger.Info("2")
+	// wait for it to wait for room in the queue
+	assert.Eventually(t, func() bool {
+		hook.mu.Lock()
+		defer hook.mu.Unlock()
+		return hook.pending == 3
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	closed := make(chan error, 1)
+	go func() { closed <- hook.Close(ctx) }()
+	select {
+	case err := <-closed:
+		assert.Equal(t, context.DeadlineExceeded, err)
+	case <-time.After(time.Second):
+		t.Fatal("Close ignored its deadline")
+	}
+}
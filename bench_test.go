@@ -0,0 +1,128 @@
+package hlog
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+// The benchmarks only use the exported API so that they can be run against
+// earlier versions and compared with benchstat.
+
+var (
+	benchErr    = errors.New("connection reset")
+	benchFields = Fields{
+		"user":    "alice",
+		"request": 42,
+		"path":    "/api/v1/items",
+		"ok":      true,
+		"took":    1.5,
+	}
+)
+
+func benchLogger(formatter Formatter, level Level) *Logger {
+	logger := New()
+	logger.Out = ioutil.Discard
+	logger.Formatter = formatter
+	logger.SetLevel(level)
+	return logger
+}
+
+func benchFormatters() []struct {
+	name      string
+	formatter func() Formatter
+} {
+	return []struct {
+		name      string
+		formatter func() Formatter
+	}{
+		{"Text", func() Formatter { return &TextFormatter{DisableColors: true} }},
+		{"JSON", func() Formatter { return &JSONFormatter{} }},
+	}
+}
+
+func BenchmarkDisabled(b *testing.B) {
+	logger := benchLogger(&JSONFormatter{}, ErrorLevel)
+	entry := logger.WithFields(benchFields)
+	cases := []struct {
+		name string
+		log  func()
+	}{
+		{"Message", func() { logger.Info("request served") }},
+		{"Printf", func() { logger.Infof("request %d served", 42) }},
+		{"WithField", func() { logger.WithField("user", "alice").Info("request served") }},
+		{"With", func() { logger.With(Str("user", "alice"), Int("request", 42)).Info("request served") }},
+		{"Entry", func() { entry.Info("request served") }},
+	}
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c.log()
+			}
+		})
+	}
+}
+
+func BenchmarkEnabled(b *testing.B) {
+	for _, f := range benchFormatters() {
+		logger := benchLogger(f.formatter(), InfoLevel)
+		entry := logger.WithFields(benchFields)
+		typed := logger.With(Str("user", "alice"), Int("request", 42), Str("path", "/api/v1/items"), Bool("ok", true), Float64("took", 1.5))
+		cases := []struct {
+			name string
+			log  func()
+		}{
+			{"Message", func() { logger.Info("request served") }},
+			{"Printf", func() { logger.Infof("request %d served", 42) }},
+			{"WithField", func() { logger.WithField("user", "alice").Info("request served") }},
+			{"WithFields", func() { logger.WithFields(benchFields).Info("request served") }},
+			{"WithError", func() { logger.WithError(benchErr).Error("request failed") }},
+			{"With", func() { logger.With(Str("user", "alice"), Int("request", 42)).Info("request served") }},
+			{"Entry", func() { entry.Info("request served") }},
+			{"TypedEntry", func() { typed.Info("request served") }},
+		}
+		for _, c := range cases {
+			b.Run(f.name+"/"+c.name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					c.log()
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkEnabledParallel(b *testing.B) {
+	for _, f := range benchFormatters() {
+		logger := benchLogger(f.formatter(), InfoLevel)
+		entry := logger.WithFields(benchFields)
+		b.Run(f.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					entry.Info("request served")
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkFormat(b *testing.B) {
+	for _, f := range benchFormatters() {
+		formatter := f.formatter()
+		entry := NewEntry(benchLogger(formatter, InfoLevel)).WithFields(benchFields)
+		entry.Time = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		entry.Level = InfoLevel
+		entry.Message = "request served"
+		b.Run(f.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := formatter.Format(entry); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package hlog
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// maximumCallerDepth restricts the look back frames to avoid runaway
+	// lookups, on top of the frames skipped with SetCallerSkip
+	maximumCallerDepth int = 25
+	// knownHlogFrames is the number of frames always found below the
+	// caller: runtime.Callers, getCaller, Entry.emit and Entry.log
+	knownHlogFrames int = 4
+)
+
+var (
+	// callerSkipPackages holds the []string of the packages added with
+	// AddCallerSkipPackages, replaced on each addition
+	callerSkipPackages atomic.Value
+	// callerCache holds the frames of the program counters resolved so
+	// far. There is one per call site, so it is bounded by the size of the
+	// program, and lookups only take the read lock.
+	callerCache = make(map[uintptr]*callerSite)
+	callerMu    sync.RWMutex
+)
+
+// callerSite holds the frames a program counter resolves to, more than one
+// when functions were inlined, innermost first.
+type callerSite struct {
+	frames []callerFrame
+}
+
+type callerFrame struct {
+	frame runtime.Frame
+	// skipped is set for the frames of hlog and of the packages added with
+	// AddCallerSkipPackages
+	skipped bool
+}
+
+// getCaller returns the first calling function outside of hlog and of the
+// packages added with AddCallerSkipPackages, skipping skip more frames. The
+// frames and whether they are skipped are resolved once per program counter,
+// the returned frame is shared and must not be modified.
+func getCaller(skip int) *runtime.Frame {
+	var buf [maximumCallerDepth]uintptr
+	pcs := buf[:]
+	if skip > 0 {
+		pcs = make([]uintptr, maximumCallerDepth+skip)
+	}
+	n := runtime.Callers(knownHlogFrames, pcs)
+	for _, pc := range pcs[:n] {
+		site := lookupCaller(pc)
+		for i := range site.frames {
+			if site.frames[i].skipped {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			return &site.frames[i].frame
+		}
+	}
+	// if we got here, we failed to find the caller's context
+	return nil
+}
+
+// callerAt returns the frame of a program counter returned by
+// runtime.Callers, shared like the ones returned by getCaller.
+func callerAt(pc uintptr) *runtime.Frame {
+	return &lookupCaller(pc).frames[0].frame
+}
+
+// lookupCaller returns the frames of a program counter, resolved on the
+// first lookup.
+func lookupCaller(pc uintptr) *callerSite {
+	callerMu.RLock()
+	site, ok := callerCache[pc]
+	callerMu.RUnlock()
+	if !ok {
+		site = resolveCaller(pc)
+	}
+	return site
+}
+
+// resolveCaller resolves the frames of a program counter and caches them.
+func resolveCaller(pc uintptr) *callerSite {
+	callerMu.Lock()
+	defer callerMu.Unlock()
+	if site, ok := callerCache[pc]; ok {
+		return site
+	}
+
+	skipped := loadCallerSkipPackages()
+	site := &callerSite{}
+	frames := runtime.CallersFrames([]uintptr{pc})
+	for {
+		f, more := frames.Next()
+		pkg := getPackageName(f.Function)
+		site.frames = append(site.frames, callerFrame{
+			frame: f,
+			// Skip this package, but not its tests, the log package calling
+			// it through StdLogger, and the wrapper packages
+			skipped: (pkg == hlogPackage && !strings.HasSuffix(f.File, "_test.go")) || pkg == stdLogPackage || skippedPackage(pkg, skipped),
+		})
+		if !more {
+			break
+		}
+	}
+
+	callerCache[pc] = site
+	return site
+}
+
+// AddCallerSkipPackages makes the reported caller skip the frames of the
+// given packages and of the packages below them, like it skips the frames of
+// hlog. Wrapper libraries call it from init with their import path so that
+// the caller is the code calling the wrapper:
+//
+//	func init() {
+//		hlog.AddCallerSkipPackages("example.com/company/log")
+//	}
+func AddCallerSkipPackages(pkgs ...string) {
+	callerMu.Lock()
+	defer callerMu.Unlock()
+	old := loadCallerSkipPackages()
+	skipped := make([]string, len(old), len(old)+len(pkgs))
+	copy(skipped, old)
+	skipped = append(skipped, pkgs...)
+	callerSkipPackages.Store(skipped)
+	// The frames resolved so far may belong to the new packages.
+	callerCache = make(map[uintptr]*callerSite)
+}
+
+func loadCallerSkipPackages() []string {
+	skipped, _ := callerSkipPackages.Load().([]string)
+	return skipped
+}
+
+// skippedPackage reports whether pkg is or is below one of the packages.
+func skippedPackage(pkg string, skipped []string) bool {
+	for _, s := range skipped {
+		if strings.HasPrefix(pkg, s) && (len(pkg) == len(s) || pkg[len(s)] == '/') {
+			return true
+		}
+	}
+	return false
+}
+
+// SetCallerSkip makes the logger skip n more frames, past the ones of hlog
+// and of the packages added with AddCallerSkipPackages, when reporting the
+// caller. It is meant for wrappers calling the logger through a known number
+// of their own functions.
+func (logger *Logger) SetCallerSkip(n int) {
+	if n < 0 {
+		n = 0
+	}
+	logger.lock().Lock()
+	logger.callerSkip = n
+	logger.lock().Unlock()
+	logger.eachChild(func(child *Logger) {
+		child.SetCallerSkip(n)
+	})
+}
+
+// WithCallerSkip makes the entry skip n more frames, on top of the ones of
+// SetCallerSkip, when reporting the caller.
+func (entry *Entry) WithCallerSkip(n int) *Entry {
+	if n < 0 {
+		n = 0
+	}
+	dup := entry.Dup()
+	dup.callerSkip = entry.callerSkip + n
+	return dup
+}
@@ -0,0 +1,140 @@
+package hlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// callerHook records the caller of the entries.
+type callerHook struct {
+	caller string
+	frame  *runtime.Frame
+}
+
+func (h *callerHook) Levels() []Level {
+	return AllLevels
+}
+
+func (h *callerHook) Fire(entry *Entry) error {
+	h.caller = ""
+	h.frame = entry.Caller
+	if entry.Caller != nil {
+		h.caller = entry.Caller.Function
+	}
+	return nil
+}
+
+func newCallerLogger() (*Logger, *callerHook) {
+	hook := new(callerHook)
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.SetReportCaller(true)
+	logger.AddHook(hook)
+	return logger, hook
+}
+
+// wrappedInfo stands for a wrapper library function.
+func wrappedInfo(logger *Logger, msg string) {
+	logger.Info(msg)
+}
+
+func TestCaller(t *testing.T) {
+	logger, hook := newCallerLogger()
+
+	logger.Info("direct")
+	assert.Equal(t, "github.com/adminhmi/hlog.TestCaller", hook.caller)
+
+	wrappedInfo(logger, "wrapped")
+	assert.Equal(t, "github.com/adminhmi/hlog.wrappedInfo", hook.caller)
+}
+
+func TestSetCallerSkip(t *testing.T) {
+	logger, hook := newCallerLogger()
+	logger.SetCallerSkip(1)
+
+	wrappedInfo(logger, "wrapped")
+	assert.Equal(t, "github.com/adminhmi/hlog.TestSetCallerSkip", hook.caller)
+
+	logger.Named("child").Info("direct")
+	assert.Equal(t, "testing.tRunner", hook.caller)
+}
+
+func TestWithCallerSkip(t *testing.T) {
+	logger, hook := newCallerLogger()
+
+	func() {
+		logger.WithField("k", "v").WithCallerSkip(1).Info("wrapped")
+	}()
+	assert.Equal(t, "github.com/adminhmi/hlog.TestWithCallerSkip", hook.caller)
+}
+
+func TestAddCallerSkipPackages(t *testing.T) {
+	saved := loadCallerSkipPackages()
+	defer func() {
+		callerSkipPackages.Store(saved)
+		callerMu.Lock()
+		callerCache = make(map[uintptr]*callerSite)
+		callerMu.Unlock()
+	}()
+	logger, hook := newCallerLogger()
+
+	AddCallerSkipPackages("github.com/adminhmi/hlog")
+	logger.Info("skipped")
+	assert.Equal(t, "testing.tRunner", hook.caller)
+}
+
+func TestSkippedPackage(t *testing.T) {
+	skipped := []string{"example.com/log", "example.com/x/wrap"}
+	require.True(t, skippedPackage("example.com/log", skipped))
+	assert.True(t, skippedPackage("example.com/log/adapter", skipped))
+	assert.True(t, skippedPackage("example.com/x/wrap", skipped))
+	assert.False(t, skippedPackage("example.com/logutil", skipped))
+	assert.False(t, skippedPackage("example.com/x", skipped))
+}
+
+func TestCallerCache(t *testing.T) {
+	logger, hook := newCallerLogger()
+
+	var frames []*runtime.Frame
+	for i := 0; i < 2; i++ {
+		logger.Info("cached")
+		assert.Equal(t, "github.com/adminhmi/hlog.TestCallerCache", hook.caller)
+		frames = append(frames, hook.frame)
+	}
+	assert.Same(t, frames[0], frames[1])
+}
+
+func benchmarkCallerTracing(b *testing.B, reportCaller bool) {
+	logger := New()
+	logger.Out = ioutil.Discard
+	logger.Formatter = &JSONFormatter{DisableTimestamp: true}
+	logger.SetReportCaller(reportCaller)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			logger.Info("benchmark")
+		}
+	})
+}
+
+func BenchmarkWithoutCallerTracing(b *testing.B) {
+	benchmarkCallerTracing(b, false)
+}
+
+func BenchmarkWithCallerTracing(b *testing.B) {
+	benchmarkCallerTracing(b, true)
+}
+
+func BenchmarkGetCaller(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		getCaller(0)
+	}
+}
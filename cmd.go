@@ -0,0 +1,127 @@
+package hlog
+
+import (
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// Keys of the fields added by AttachCmd.
+const (
+	FieldKeyCmd      = "cmd"
+	FieldKeyPid      = "pid"
+	FieldKeyStream   = "stream"
+	FieldKeyExitCode = "exit_code"
+	FieldKeyDuration = "duration"
+)
+
+// CmdOptions configures AttachCmd.
+type CmdOptions struct {
+	// StdoutLevel is the level of the lines written to stdout when no other
+	// level is detected, InfoLevel when zero.
+	StdoutLevel Level
+	// StderrLevel is the level of the lines written to stderr when no other
+	// level is detected, WarnLevel when zero.
+	StderrLevel Level
+	// Name is the value of the cmd field, the base name of the path of the
+	// command by default.
+	Name string
+	// Lines configures the level detection and the parsing of the lines.
+	Lines LineWriterOptions
+}
+
+// AttachedCmd is a command whose output and exit are logged, see
+// Logger.AttachCmd. Its Start, Wait and Run methods must be used instead of
+// the ones of the exec.Cmd.
+type AttachedCmd struct {
+	*exec.Cmd
+
+	entry  *Entry
+	stdout *LineWriter
+	stderr *LineWriter
+	start  time.Time
+}
+
+// AttachCmd logs the output of the command, see Entry.AttachCmd.
+func (logger *Logger) AttachCmd(cmd *exec.Cmd, opts CmdOptions) *AttachedCmd {
+	return NewEntry(logger).AttachCmd(cmd, opts)
+}
+
+// AttachCmd makes each line the command writes to stdout and stderr an
+// entry, with the fields of the entry and the `cmd`, `pid` and `stream`
+// fields. The lines go through a LineWriter per stream, which detects their
+// level and parses them. When the command finishes, Wait logs its
+// `exit_code` and `duration`, at InfoLevel or at ErrorLevel when it failed:
+//
+//	c := logger.AttachCmd(exec.Command("backup", "--all"), hlog.CmdOptions{})
+//	if err := c.Run(); err != nil {
+//		...
+//	}
+//
+// The output is copied by the goroutines of the exec.Cmd, they are done once
+// Wait returns. A child process left running with the output of the command
+// keeps Wait waiting, like with any exec.Cmd.
+func (entry *Entry) AttachCmd(cmd *exec.Cmd, opts CmdOptions) *AttachedCmd {
+	if opts.StdoutLevel == PanicLevel {
+		opts.StdoutLevel = InfoLevel
+	}
+	if opts.StderrLevel == PanicLevel {
+		opts.StderrLevel = WarnLevel
+	}
+	if opts.Name == "" {
+		opts.Name = filepath.Base(cmd.Path)
+	}
+
+	c := &AttachedCmd{Cmd: cmd}
+	// The pid is only known once the command is started.
+	c.entry = entry.WithFields(Fields{FieldKeyCmd: opts.Name, FieldKeyPid: Lazy(c.pid)})
+	c.stdout = c.entry.WithField(FieldKeyStream, "stdout").LineWriter(opts.StdoutLevel, opts.Lines)
+	c.stderr = c.entry.WithField(FieldKeyStream, "stderr").LineWriter(opts.StderrLevel, opts.Lines)
+	cmd.Stdout = c.stdout
+	cmd.Stderr = c.stderr
+	return c
+}
+
+func (c *AttachedCmd) pid() interface{} {
+	if c.Process == nil {
+		return nil
+	}
+	return c.Process.Pid
+}
+
+// Start starts the command, logging the error if it can't be started.
+func (c *AttachedCmd) Start() error {
+	c.start = time.Now()
+	if err := c.Cmd.Start(); err != nil {
+		c.entry.WithError(err).Error("command failed to start")
+		return err
+	}
+	return nil
+}
+
+// Wait waits for the command to exit and for its output to be logged, then
+// logs its exit status and duration.
+func (c *AttachedCmd) Wait() error {
+	err := c.Cmd.Wait()
+	c.stdout.Close()
+	c.stderr.Close()
+
+	entry := c.entry.With(Dur(FieldKeyDuration, time.Since(c.start)))
+	if c.ProcessState != nil {
+		entry = entry.With(Int(FieldKeyExitCode, c.ProcessState.ExitCode()))
+	}
+	if err != nil {
+		entry.WithError(err).Error("command failed")
+	} else {
+		entry.Info("command exited")
+	}
+	return err
+}
+
+// Run starts the command and waits for it, see Start and Wait.
+func (c *AttachedCmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
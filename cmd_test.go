@@ -0,0 +1,90 @@
+package hlog
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAttachCmdHelper is the command run by the AttachCmd tests.
+func TestAttachCmdHelper(t *testing.T) {
+	if os.Getenv("HLOG_CMD_HELPER") != "1" {
+		t.Skip("helper process")
+	}
+	fmt.Println("copying files")
+	fmt.Fprintln(os.Stderr, `{"level":"error","msg":"disk full","free":0}`)
+	fmt.Fprint(os.Stderr, "no newline")
+	os.Exit(3)
+}
+
+func TestAttachCmd(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+
+	goroutines := runtime.NumGoroutine()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestAttachCmdHelper$")
+	cmd.Env = append(os.Environ(), "HLOG_CMD_HELPER=1")
+	c := logger.WithField("job", "backup").AttachCmd(cmd, CmdOptions{Name: "helper"})
+	err := c.Run()
+	require.Error(t, err)
+	pid := float64(cmd.Process.Pid)
+
+	lines := decodeLines(t, &buffer)
+	require.Len(t, lines, 4)
+	for _, fields := range lines {
+		assert.Equal(t, "helper", fields[FieldKeyCmd])
+		assert.Equal(t, pid, fields[FieldKeyPid])
+		assert.Equal(t, "backup", fields["job"])
+	}
+
+	// stdout and stderr are copied by different goroutines, in any order
+	byMsg := map[interface{}]Fields{}
+	for _, fields := range lines[:3] {
+		byMsg[fields["msg"]] = fields
+	}
+	require.Len(t, byMsg, 3)
+	assert.Equal(t, "info", byMsg["copying files"]["level"])
+	assert.Equal(t, "stdout", byMsg["copying files"][FieldKeyStream])
+	assert.Equal(t, "error", byMsg["disk full"]["level"])
+	assert.Equal(t, float64(0), byMsg["disk full"]["free"])
+	assert.Equal(t, "stderr", byMsg["disk full"][FieldKeyStream])
+	assert.Equal(t, "warning", byMsg["no newline"]["level"])
+	assert.Equal(t, "stderr", byMsg["no newline"][FieldKeyStream])
+
+	assert.Equal(t, "error", lines[3]["level"])
+	assert.Equal(t, "command failed", lines[3]["msg"])
+	assert.Equal(t, float64(3), lines[3][FieldKeyExitCode])
+	assert.NotEmpty(t, lines[3][FieldKeyDuration])
+	assert.NotContains(t, lines[3], FieldKeyStream)
+
+	// assert.Eventually would count its own goroutines.
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > goroutines && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+}
+
+func TestAttachCmdStartError(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+
+	c := logger.AttachCmd(exec.Command("/nonexistent/hlog-command"), CmdOptions{})
+	require.Error(t, c.Run())
+
+	lines := decodeLines(t, &buffer)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "command failed to start", lines[0]["msg"])
+	assert.Equal(t, "hlog-command", lines[0][FieldKeyCmd])
+	assert.Nil(t, lines[0][FieldKeyPid])
+}
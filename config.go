@@ -0,0 +1,442 @@
+package hlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of the environment variables read by LoadEnv.
+const EnvPrefix = "HLOG_"
+
+// Config describes how to set up a Logger. It can be loaded from a JSON or
+// YAML file with LoadConfigFile, from HLOG_* environment variables with
+// LoadConfigEnv, and applied with Configure.
+type Config struct {
+	// Level of the logger, e.g. "info". Left unchanged when empty.
+	Level string `json:"level" yaml:"level" env:"LEVEL"`
+	// Levels of the named loggers, see Logger.SetLevels.
+	Levels string `json:"levels" yaml:"levels" env:"LEVELS"`
+	// Output is "stdout", "stderr", "discard" or the path of a file to append
+	// to. Left unchanged when empty.
+	Output string `json:"output" yaml:"output" env:"OUTPUT"`
+	// ReportCaller enables or disables the func and file fields. Left
+	// unchanged when nil.
+	ReportCaller *bool `json:"report_caller" yaml:"report_caller" env:"REPORT_CALLER"`
+	// StackLevel enables stack traces from that level, see
+	// Logger.SetStackLevel. Left unchanged when empty.
+	StackLevel string `json:"stack_level" yaml:"stack_level" env:"STACK_LEVEL"`
+	// Formatter selects and configures the formatter. The formatter is left
+	// unchanged when no option is set.
+	Formatter FormatterConfig `json:"formatter" yaml:"formatter"`
+	// Hooks declares the hooks to install, by the name they were registered
+	// with in RegisterHook. When set they replace the hooks of the logger.
+	Hooks []HookConfig `json:"hooks" yaml:"hooks"`
+}
+
+// FormatterConfig holds the options of the built-in formatters. Options only
+// known to one formatter are ignored by the other.
+type FormatterConfig struct {
+	// Type is "text" (the default) or "json".
+	Type             string            `json:"type" yaml:"type" env:"FORMATTER"`
+	TimestampFormat  string            `json:"timestamp_format" yaml:"timestamp_format" env:"TIMESTAMP_FORMAT"`
+	DisableTimestamp bool              `json:"disable_timestamp" yaml:"disable_timestamp" env:"DISABLE_TIMESTAMP"`
+	FieldMap         map[string]string `json:"field_map" yaml:"field_map" env:"FIELD_MAP"`
+
+	// TextFormatter options
+	ForceColors            bool   `json:"force_colors" yaml:"force_colors" env:"FORCE_COLORS"`
+	DisableColors          bool   `json:"disable_colors" yaml:"disable_colors" env:"DISABLE_COLORS"`
+	ForceQuote             bool   `json:"force_quote" yaml:"force_quote" env:"FORCE_QUOTE"`
+	DisableQuote           bool   `json:"disable_quote" yaml:"disable_quote" env:"DISABLE_QUOTE"`
+	FullTimestamp          bool   `json:"full_timestamp" yaml:"full_timestamp" env:"FULL_TIMESTAMP"`
+	DisableSorting         bool   `json:"disable_sorting" yaml:"disable_sorting" env:"DISABLE_SORTING"`
+	DisableLevelTruncation bool   `json:"disable_level_truncation" yaml:"disable_level_truncation" env:"DISABLE_LEVEL_TRUNCATION"`
+	PadLevelText           bool   `json:"pad_level_text" yaml:"pad_level_text" env:"PAD_LEVEL_TEXT"`
+	QuoteEmptyFields       bool   `json:"quote_empty_fields" yaml:"quote_empty_fields" env:"QUOTE_EMPTY_FIELDS"`
+	ForceFormatting        bool   `json:"force_formatting" yaml:"force_formatting" env:"FORCE_FORMATTING"`
+	DisableUppercase       bool   `json:"disable_uppercase" yaml:"disable_uppercase" env:"DISABLE_UPPERCASE"`
+	QuoteCharacter         string `json:"quote_character" yaml:"quote_character" env:"QUOTE_CHARACTER"`
+	SpacePadding           int    `json:"space_padding" yaml:"space_padding" env:"SPACE_PADDING"`
+
+	// JSONFormatter options
+	DisableHTMLEscape bool   `json:"disable_html_escape" yaml:"disable_html_escape" env:"DISABLE_HTML_ESCAPE"`
+	DataKey           string `json:"data_key" yaml:"data_key" env:"DATA_KEY"`
+	PrettyPrint       bool   `json:"pretty_print" yaml:"pretty_print" env:"PRETTY_PRINT"`
+}
+
+// HookConfig declares a hook registered with RegisterHook.
+type HookConfig struct {
+	Name    string                 `json:"name" yaml:"name"`
+	Options map[string]interface{} `json:"options" yaml:"options"`
+}
+
+// HookFactory builds a hook from the options of a HookConfig.
+type HookFactory func(options map[string]interface{}) (Hook, error)
+
+var (
+	hookFactoriesMu sync.RWMutex
+	hookFactories   = map[string]HookFactory{}
+)
+
+// RegisterHook makes a hook available to Configure under the given name.
+// Hook packages register themselves in their init function, so importing
+// them for side effects is enough to use them from a configuration file.
+func RegisterHook(name string, factory HookFactory) {
+	hookFactoriesMu.Lock()
+	defer hookFactoriesMu.Unlock()
+	hookFactories[name] = factory
+}
+
+// RegisteredHooks returns the sorted names of the registered hooks.
+func RegisteredHooks() []string {
+	hookFactoriesMu.RLock()
+	defer hookFactoriesMu.RUnlock()
+	names := make([]string, 0, len(hookFactories))
+	for name := range hookFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DecodeHookOptions decodes hook options into v, a pointer to a struct with
+// json tags. It is meant to be used by HookFactory implementations.
+func DecodeHookOptions(options map[string]interface{}, v interface{}) error {
+	b, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// LoadConfigFile reads a configuration from a JSON or YAML file, the format
+// is chosen from the file extension.
+func LoadConfigFile(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(b, cfg)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, cfg)
+	default:
+		return nil, fmt.Errorf("unknown hlog config file extension %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse hlog config %s, %w", path, err)
+	}
+	return cfg, nil
+}
+
+// LoadConfigEnv reads a configuration from the HLOG_* environment variables.
+func LoadConfigEnv() (*Config, error) {
+	cfg := &Config{}
+	return cfg, cfg.LoadEnv()
+}
+
+// LoadEnv overrides the configuration with the HLOG_* environment variables
+// that are set, e.g. HLOG_LEVEL=debug or HLOG_FIELD_MAP=time=@timestamp,msg=message.
+//
+// HLOG_HOOKS holds a comma separated list of hook names, the options of
+// each hook are read from HLOG_HOOK_<NAME>_<OPTION> variables. Option values
+// are decoded as JSON when possible and kept as strings otherwise.
+func (c *Config) LoadEnv() error {
+	if err := loadEnvStruct(reflect.ValueOf(c).Elem()); err != nil {
+		return err
+	}
+	if err := loadEnvStruct(reflect.ValueOf(&c.Formatter).Elem()); err != nil {
+		return err
+	}
+
+	names, ok := os.LookupEnv(EnvPrefix + "HOOKS")
+	if !ok {
+		return nil
+	}
+	c.Hooks = nil
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		hook := HookConfig{Name: name, Options: map[string]interface{}{}}
+		prefix := EnvPrefix + "HOOK_" + strings.ToUpper(name) + "_"
+		for _, kv := range os.Environ() {
+			i := strings.Index(kv, "=")
+			if i < 0 || !strings.HasPrefix(kv[:i], prefix) {
+				continue
+			}
+			var value interface{}
+			if err := json.Unmarshal([]byte(kv[i+1:]), &value); err != nil {
+				value = kv[i+1:]
+			}
+			hook.Options[strings.ToLower(kv[len(prefix):i])] = value
+		}
+		c.Hooks = append(c.Hooks, hook)
+	}
+	return nil
+}
+
+// loadEnvStruct sets the fields of v that have an env tag.
+func loadEnvStruct(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("env")
+		if tag == "" {
+			continue
+		}
+		value, ok := os.LookupEnv(EnvPrefix + tag)
+		if !ok {
+			continue
+		}
+		field := v.Field(i)
+		if field.Kind() == reflect.Ptr {
+			field.Set(reflect.New(field.Type().Elem()))
+			field = field.Elem()
+		}
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s%s: %w", EnvPrefix, tag, err)
+			}
+			field.SetBool(b)
+		case reflect.Int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s%s: %w", EnvPrefix, tag, err)
+			}
+			field.SetInt(int64(n))
+		case reflect.Map:
+			m := make(map[string]string)
+			for _, kv := range strings.Split(value, ",") {
+				if kv = strings.TrimSpace(kv); kv == "" {
+					continue
+				}
+				j := strings.Index(kv, "=")
+				if j < 0 {
+					return fmt.Errorf("invalid %s%s entry %q", EnvPrefix, tag, kv)
+				}
+				m[kv[:j]] = kv[j+1:]
+			}
+			field.Set(reflect.ValueOf(m))
+		}
+	}
+	return nil
+}
+
+// Configure applies the configuration to the standard logger.
+func Configure(cfg *Config) error {
+	return std.Configure(cfg)
+}
+
+// Configure applies the configuration to the logger. Nothing is changed if
+// any part of the configuration is invalid. The output and hooks replaced by
+// the configuration are flushed and closed, files included.
+func (logger *Logger) Configure(cfg *Config) error {
+	var level Level
+	var err error
+	if cfg.Level != "" {
+		if level, err = ParseLevel(cfg.Level); err != nil {
+			return err
+		}
+	}
+	var stackLevel Level
+	if cfg.StackLevel != "" {
+		if stackLevel, err = ParseLevel(cfg.StackLevel); err != nil {
+			return err
+		}
+	}
+	var levels *levelSpec
+	if cfg.Levels != "" {
+		if levels, err = parseLevels(cfg.Levels); err != nil {
+			return err
+		}
+	}
+	var formatter Formatter
+	if !cfg.Formatter.isZero() {
+		if formatter, err = cfg.Formatter.build(); err != nil {
+			return err
+		}
+	}
+	// The output is opened first, it can fail without anything to undo
+	// while hooks may already be connected.
+	var out io.Writer
+	if cfg.Output != "" {
+		if out, err = openOutput(cfg.Output); err != nil {
+			return err
+		}
+	}
+	var hooks LevelHooks
+	if len(cfg.Hooks) > 0 {
+		hooks = make(LevelHooks)
+		built := make([]Hook, 0, len(cfg.Hooks))
+		for _, hc := range cfg.Hooks {
+			hook, err := hc.build()
+			if err != nil {
+				releaseConfigured(built, out)
+				return err
+			}
+			built = append(built, hook)
+			hooks.Add(hook)
+		}
+	}
+
+	if cfg.Level != "" {
+		logger.SetLevel(level)
+	}
+	if levels != nil {
+		logger.applyLevels(levels)
+	}
+	if formatter != nil {
+		logger.SetFormatter(formatter)
+	}
+	if cfg.ReportCaller != nil {
+		logger.SetReportCaller(*cfg.ReportCaller)
+	}
+	if cfg.StackLevel != "" {
+		logger.SetStackLevel(stackLevel)
+	}
+	var oldOut io.Writer
+	if out != nil {
+		logger.lock().Lock()
+		oldOut = logger.Out
+		logger.lock().Unlock()
+		logger.SetOutput(out)
+	}
+	var oldHooks []Hook
+	if hooks != nil {
+		oldHooks = logger.ReplaceHooks(hooks).unique()
+	}
+	releaseConfigured(oldHooks, oldOut)
+	return nil
+}
+
+// isZero reports whether no formatter option is set.
+func (fc *FormatterConfig) isZero() bool {
+	return reflect.ValueOf(fc).Elem().IsZero()
+}
+
+func (fc *FormatterConfig) build() (Formatter, error) {
+	var fieldMap FieldMap
+	if len(fc.FieldMap) > 0 {
+		fieldMap = make(FieldMap, len(fc.FieldMap))
+		for k, v := range fc.FieldMap {
+			fieldMap[fieldKey(k)] = v
+		}
+	}
+	switch strings.ToLower(fc.Type) {
+	case "", "text":
+		return &TextFormatter{
+			ForceColors:            fc.ForceColors,
+			DisableColors:          fc.DisableColors,
+			ForceQuote:             fc.ForceQuote,
+			DisableQuote:           fc.DisableQuote,
+			DisableTimestamp:       fc.DisableTimestamp,
+			FullTimestamp:          fc.FullTimestamp,
+			TimestampFormat:        fc.TimestampFormat,
+			DisableSorting:         fc.DisableSorting,
+			DisableLevelTruncation: fc.DisableLevelTruncation,
+			PadLevelText:           fc.PadLevelText,
+			QuoteEmptyFields:       fc.QuoteEmptyFields,
+			FieldMap:               fieldMap,
+			ForceFormatting:        fc.ForceFormatting,
+			DisableUppercase:       fc.DisableUppercase,
+			QuoteCharacter:         fc.QuoteCharacter,
+			SpacePadding:           fc.SpacePadding,
+		}, nil
+	case "json":
+		return &JSONFormatter{
+			TimestampFormat:   fc.TimestampFormat,
+			DisableTimestamp:  fc.DisableTimestamp,
+			DisableHTMLEscape: fc.DisableHTMLEscape,
+			DataKey:           fc.DataKey,
+			FieldMap:          fieldMap,
+			PrettyPrint:       fc.PrettyPrint,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown hlog formatter %q", fc.Type)
+}
+
+func (hc *HookConfig) build() (Hook, error) {
+	hookFactoriesMu.RLock()
+	factory, ok := hookFactories[hc.Name]
+	hookFactoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown hlog hook %q", hc.Name)
+	}
+	options := hc.Options
+	if options == nil {
+		options = map[string]interface{}{}
+	}
+	hook, err := factory(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hlog hook %q, %w", hc.Name, err)
+	}
+	return hook, nil
+}
+
+// releaseConfigured flushes and closes the hooks and the output replaced by
+// Configure, or the ones it built when the configuration turns out to be
+// invalid. Stdout and stderr stay open.
+func releaseConfigured(hooks []Hook, out io.Writer) {
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	var errs HookErrors
+	for _, hook := range hooks {
+		if err := flushTarget(ctx, hook); err != nil {
+			errs = append(errs, &HookError{Hook: hook, Err: err})
+		}
+		var err error
+		if c, ok := hook.(io.Closer); ok {
+			err = c.Close()
+		} else {
+			err = closeTarget(ctx, hook)
+		}
+		if err != nil {
+			errs = append(errs, &HookError{Hook: hook, Err: err})
+		}
+	}
+	if out != nil {
+		if err := flushTarget(ctx, out); err != nil {
+			errs = append(errs, &HookError{Err: fmt.Errorf("failed to flush log output, %w", err)})
+		}
+		err := closeTarget(ctx, out)
+		if f, ok := out.(*os.File); ok && f != os.Stdout && f != os.Stderr {
+			err = f.Close()
+		}
+		if err != nil {
+			errs = append(errs, &HookError{Err: fmt.Errorf("failed to close log output, %w", err)})
+		}
+	}
+	if len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "Failed to release replaced log output, %v\n", errs)
+	}
+}
+
+func openOutput(output string) (io.Writer, error) {
+	switch strings.ToLower(output) {
+	case "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	case "discard", "null":
+		return ioutil.Discard, nil
+	}
+	return os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+}
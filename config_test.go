@@ -0,0 +1,212 @@
+package hlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func setEnv(t *testing.T, key, value string) {
+	old, ok := os.LookupEnv(key)
+	require.NoError(t, os.Setenv(key, value))
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	jsonPath := writeConfig(t, "hlog.json", `{
+		"level": "debug",
+		"levels": "db=warn",
+		"formatter": {"type": "json", "field_map": {"msg": "message"}}
+	}`)
+	yamlPath := writeConfig(t, "hlog.yaml", `
+level: debug
+levels: db=warn
+formatter:
+  type: json
+  field_map:
+    msg: message
+`)
+
+	for _, path := range []string{jsonPath, yamlPath} {
+		cfg, err := LoadConfigFile(path)
+		require.NoError(t, err, path)
+		assert.Equal(t, "debug", cfg.Level)
+		assert.Equal(t, "db=warn", cfg.Levels)
+		assert.Equal(t, "json", cfg.Formatter.Type)
+		assert.Equal(t, map[string]string{"msg": "message"}, cfg.Formatter.FieldMap)
+	}
+
+	_, err := LoadConfigFile(writeConfig(t, "hlog.toml", ""))
+	assert.Error(t, err)
+}
+
+func TestLoadConfigEnv(t *testing.T) {
+	setEnv(t, "HLOG_LEVEL", "warn")
+	setEnv(t, "HLOG_REPORT_CALLER", "true")
+	setEnv(t, "HLOG_FORMATTER", "json")
+	setEnv(t, "HLOG_FIELD_MAP", "time=@timestamp,msg=message")
+	setEnv(t, "HLOG_HOOKS", "test")
+	setEnv(t, "HLOG_HOOK_TEST_ADDR", "localhost:1234")
+	setEnv(t, "HLOG_HOOK_TEST_ASYNC", "true")
+
+	cfg, err := LoadConfigEnv()
+	require.NoError(t, err)
+	assert.Equal(t, "warn", cfg.Level)
+	require.NotNil(t, cfg.ReportCaller)
+	assert.True(t, *cfg.ReportCaller)
+	assert.Equal(t, "json", cfg.Formatter.Type)
+	assert.Equal(t, map[string]string{"time": "@timestamp", "msg": "message"}, cfg.Formatter.FieldMap)
+	require.Len(t, cfg.Hooks, 1)
+	assert.Equal(t, "test", cfg.Hooks[0].Name)
+	assert.Equal(t, map[string]interface{}{"addr": "localhost:1234", "async": true}, cfg.Hooks[0].Options)
+
+	setEnv(t, "HLOG_REPORT_CALLER", "maybe")
+	_, err = LoadConfigEnv()
+	assert.Error(t, err)
+}
+
+func TestConfigure(t *testing.T) {
+	hook := new(fieldsHook)
+	RegisterHook("config-test", func(options map[string]interface{}) (Hook, error) {
+		var opts struct {
+			Name string `json:"name"`
+		}
+		if err := DecodeHookOptions(options, &opts); err != nil {
+			return nil, err
+		}
+		assert.Equal(t, "collector", opts.Name)
+		return hook, nil
+	})
+	assert.Contains(t, RegisteredHooks(), "config-test")
+
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	db := logger.Named("db")
+
+	err := logger.Configure(&Config{
+		Level:  "debug",
+		Levels: "db=warn",
+		Formatter: FormatterConfig{
+			Type:     "json",
+			FieldMap: map[string]string{"msg": "message"},
+		},
+		Hooks: []HookConfig{{Name: "config-test", Options: map[string]interface{}{"name": "collector"}}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, DebugLevel, logger.GetLevel())
+	assert.Equal(t, WarnLevel, db.GetLevel())
+
+	logger.Debug("configured")
+	var fields Fields
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &fields))
+	assert.Equal(t, "configured", fields["message"])
+	assert.NotNil(t, hook.data)
+}
+
+func TestConfigureInvalid(t *testing.T) {
+	logger := New()
+	formatter := logger.Formatter
+
+	for _, cfg := range []*Config{
+		{Level: "loud"},
+		{Levels: "db=loud"},
+		{Formatter: FormatterConfig{Type: "xml"}},
+		{Hooks: []HookConfig{{Name: "unknown"}}},
+	} {
+		assert.Error(t, logger.Configure(cfg))
+	}
+	assert.Equal(t, InfoLevel, logger.GetLevel())
+	assert.Same(t, formatter, logger.Formatter)
+}
+
+func TestConfigurePartial(t *testing.T) {
+	logger := New()
+	formatter := &JSONFormatter{}
+	logger.SetFormatter(formatter)
+	logger.SetReportCaller(true)
+
+	require.NoError(t, logger.Configure(&Config{Level: "debug"}))
+	assert.Equal(t, DebugLevel, logger.GetLevel())
+	assert.Same(t, formatter, logger.Formatter)
+	assert.True(t, logger.ReportCaller)
+
+	reportCaller := false
+	require.NoError(t, logger.Configure(&Config{ReportCaller: &reportCaller, Formatter: FormatterConfig{DisableTimestamp: true}}))
+	assert.False(t, logger.ReportCaller)
+	assert.IsType(t, &TextFormatter{}, logger.Formatter)
+}
+
+func TestConfigureInvalidReleasesHooks(t *testing.T) {
+	var built []*lifecycleHook
+	RegisterHook("config-release-test", func(map[string]interface{}) (Hook, error) {
+		hook := new(lifecycleHook)
+		built = append(built, hook)
+		return hook, nil
+	})
+	logger := New()
+	hooks := []HookConfig{{Name: "config-release-test"}, {Name: "unknown"}}
+
+	// an invalid output fails before any hook is built
+	assert.Error(t, logger.Configure(&Config{
+		Output: filepath.Join(t.TempDir(), "missing", "hlog.log"),
+		Hooks:  hooks,
+	}))
+	assert.Empty(t, built)
+
+	// the hooks built before an invalid one are closed
+	assert.Error(t, logger.Configure(&Config{
+		Output: filepath.Join(t.TempDir(), "hlog.log"),
+		Hooks:  hooks,
+	}))
+	require.Len(t, built, 1)
+	assert.Equal(t, []string{"flush", "close"}, built[0].calls)
+	assert.Empty(t, logger.Hooks)
+}
+
+func TestConfigureReleasesReplaced(t *testing.T) {
+	var built []*lifecycleHook
+	RegisterHook("config-replace-test", func(map[string]interface{}) (Hook, error) {
+		hook := new(lifecycleHook)
+		built = append(built, hook)
+		return hook, nil
+	})
+	dir := t.TempDir()
+	logger := New()
+	hooks := []HookConfig{{Name: "config-replace-test"}}
+
+	require.NoError(t, logger.Configure(&Config{Output: filepath.Join(dir, "first.log"), Hooks: hooks}))
+	first, ok := logger.Out.(*os.File)
+	require.True(t, ok)
+	require.NoError(t, logger.Configure(&Config{Output: filepath.Join(dir, "second.log"), Hooks: hooks}))
+
+	_, err := first.Write([]byte("closed\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+	require.Len(t, built, 2)
+	assert.Equal(t, []string{"flush", "close"}, built[0].calls)
+	assert.Empty(t, built[1].calls)
+
+	// stdout stays open
+	require.NoError(t, logger.Configure(&Config{Output: "stdout"}))
+	require.NoError(t, logger.Configure(&Config{Output: "discard"}))
+	_, err = os.Stdout.Stat()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,143 @@
+package hlog
+
+import (
+	"context"
+)
+
+type contextKey struct{}
+
+// contextFields are the fields carried by a context.
+type contextFields struct {
+	data   Fields
+	fields []Field
+	entry  *Entry
+}
+
+// NewContext returns a copy of ctx carrying the fields, on top of those
+// already carried by ctx. Entries given the context with WithContext get
+// the fields when they are logged, unless they have fields with the same keys.
+func NewContext(ctx context.Context, fields Fields) context.Context {
+	parent, _ := ctx.Value(contextKey{}).(*contextFields)
+	carried := &contextFields{data: make(Fields, len(fields))}
+	if parent != nil {
+		carried.data = make(Fields, len(parent.data)+len(fields))
+		for k, v := range parent.data {
+			carried.data[k] = v
+		}
+		carried.fields = fieldsWithout(parent.fields, fields)
+		carried.entry = parent.entry
+	}
+	for k, v := range fields {
+		carried.data[k] = v
+	}
+	return context.WithValue(ctx, contextKey{}, carried)
+}
+
+// NewEntryContext returns a copy of ctx carrying the entry. Its fields are
+// carried like with NewContext and EntryFromContext returns it.
+func NewEntryContext(ctx context.Context, entry *Entry) context.Context {
+	ctx = NewContext(ctx, entry.Data)
+	carried := ctx.Value(contextKey{}).(*contextFields)
+	fields := make([]Field, len(carried.fields), len(carried.fields)+len(entry.fields))
+	copy(fields, carried.fields)
+	for _, f := range entry.fields {
+		if i := fieldIndex(fields, f.key); i >= 0 {
+			fields[i] = f
+		} else {
+			fields = append(fields, f)
+		}
+	}
+	carried.fields = fields
+	carried.entry = entry
+	return ctx
+}
+
+// FromContext returns a copy of the fields carried by ctx, nil if there is
+// none.
+func FromContext(ctx context.Context) Fields {
+	if ctx == nil {
+		return nil
+	}
+	carried, ok := ctx.Value(contextKey{}).(*contextFields)
+	if !ok {
+		return nil
+	}
+	fields := make(Fields, len(carried.data)+len(carried.fields))
+	for k, v := range carried.data {
+		fields[k] = v
+	}
+	for _, f := range carried.fields {
+		fields[f.key] = f.Value()
+	}
+	return fields
+}
+
+// EntryFromContext returns the entry carried by ctx with the context set, or
+// an entry of the standard logger with the context if there is none. A nil
+// ctx gives an entry of the standard logger without context.
+func EntryFromContext(ctx context.Context) *Entry {
+	if ctx == nil {
+		return NewEntry(std)
+	}
+	if carried, ok := ctx.Value(contextKey{}).(*contextFields); ok && carried.entry != nil {
+		return carried.entry.WithContext(ctx)
+	}
+	return std.WithContext(ctx)
+}
+
+// mergeContext adds the fields carried by the entry context that the entry
+// does not have. It must only be called on entries owning their Data map,
+// as done in log.
+func (entry *Entry) mergeContext() {
+	carried, ok := entry.Context.Value(contextKey{}).(*contextFields)
+	if !ok {
+		return
+	}
+	for k, v := range carried.data {
+		if _, ok := entry.Data[k]; !ok && fieldIndex(entry.fields, k) < 0 {
+			entry.Data[k] = v
+		}
+	}
+	var fields []Field
+	for _, f := range carried.fields {
+		if _, ok := entry.Data[f.key]; !ok && fieldIndex(entry.fields, f.key) < 0 {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) > 0 {
+		entry.fields = append(fields, entry.fields...)
+	}
+}
+
+// ContextExtractor returns fields to add to an entry from its context, e.g.
+// a trace ID or the authenticated user.
+type ContextExtractor func(ctx context.Context) Fields
+
+// AddContextExtractor adds an extractor called when an entry with a context
+// is logged. The fields it returns are added to the entry unless the entry,
+// or an earlier extractor, already has fields with the same keys. Like any
+// field, they are prefixed by the formatters when they clash with the
+// builtin ones.
+func (logger *Logger) AddContextExtractor(extractor ContextExtractor) {
+	logger.lock().Lock()
+	// copy so that entries being logged keep a consistent list
+	extractors := make([]ContextExtractor, len(logger.extractors), len(logger.extractors)+1)
+	copy(extractors, logger.extractors)
+	logger.extractors = append(extractors, extractor)
+	logger.lock().Unlock()
+	logger.eachChild(func(child *Logger) {
+		child.AddContextExtractor(extractor)
+	})
+}
+
+// extractContext adds the fields returned by the extractors. It must only
+// be called on entries owning their Data map, as done in log.
+func (entry *Entry) extractContext(extractors []ContextExtractor) {
+	for _, extractor := range extractors {
+		for k, v := range extractor(entry.Context) {
+			if _, ok := entry.Data[k]; !ok && fieldIndex(entry.fields, k) < 0 {
+				entry.Data[k] = v
+			}
+		}
+	}
+}
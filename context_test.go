@@ -0,0 +1,84 @@
+package hlog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextFields(t *testing.T) {
+	ctx := NewContext(context.Background(), Fields{"request_id": "r1", "user": "alice"})
+	ctx = NewContext(ctx, Fields{"user": "bob"})
+
+	assert.Equal(t, Fields{"request_id": "r1", "user": "bob"}, FromContext(ctx))
+	assert.Nil(t, FromContext(context.Background()))
+
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.WithContext(ctx).WithField("user", "carol").Info("hello")
+	}, func(fields Fields) {
+		assert.Equal(t, "r1", fields["request_id"])
+		assert.Equal(t, "carol", fields["user"])
+	})
+
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.WithContext(ctx).With(Str("request_id", "r2")).Info("hello")
+	}, func(fields Fields) {
+		assert.Equal(t, "r2", fields["request_id"])
+		assert.Equal(t, "bob", fields["user"])
+	})
+}
+
+func TestEntryContext(t *testing.T) {
+	logger := New()
+	entry := logger.WithField("component", "api").With(Int("shard", 3))
+	ctx := NewEntryContext(context.Background(), entry)
+	ctx = NewContext(ctx, Fields{"request_id": "r1"})
+
+	assert.Equal(t, Fields{"component": "api", "shard": int64(3), "request_id": "r1"}, FromContext(ctx))
+
+	fromCtx := EntryFromContext(ctx)
+	assert.Same(t, logger, fromCtx.Logger)
+	assert.Equal(t, ctx, fromCtx.Context)
+	assert.Same(t, std, EntryFromContext(context.Background()).Logger)
+	var nilCtx context.Context
+	fromNil := EntryFromContext(nilCtx)
+	assert.Same(t, std, fromNil.Logger)
+	assert.Nil(t, fromNil.Context)
+
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.WithContext(ctx).Info("hello")
+	}, func(fields Fields) {
+		assert.Equal(t, "api", fields["component"])
+		assert.Equal(t, float64(3), fields["shard"])
+		assert.Equal(t, "r1", fields["request_id"])
+	})
+}
+
+type tenantKey struct{}
+
+func TestContextExtractor(t *testing.T) {
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	ctx = NewContext(ctx, Fields{"request_id": "r1"})
+	extract := func(ctx context.Context) Fields {
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+		return Fields{"tenant": tenant, "request_id": "extracted", "msg": "clash"}
+	}
+
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.AddContextExtractor(extract)
+		log.WithContext(ctx).Info("hello")
+	}, func(fields Fields) {
+		assert.Equal(t, "acme", fields["tenant"])
+		assert.Equal(t, "r1", fields["request_id"])
+		assert.Equal(t, "hello", fields["msg"])
+		assert.Equal(t, "clash", fields["fields.msg"])
+	})
+
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.AddContextExtractor(extract)
+		log.Named("child").Info("no context")
+	}, func(fields Fields) {
+		assert.NotContains(t, fields, "tenant")
+	})
+}
@@ -0,0 +1,211 @@
+package hlog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Keys of the fields added to the summaries emitted by a Deduper.
+const (
+	FieldKeyRepeated  = "repeated"
+	FieldKeyFirstTime = "first_time"
+	FieldKeyLastTime  = "last_time"
+)
+
+// DefaultDedupWindow is the Window of a Deduper not in Consecutive mode
+// created without one.
+var DefaultDedupWindow = time.Minute
+
+// DedupOptions configures a Deduper.
+type DedupOptions struct {
+	// Window is how long duplicates of an entry are swallowed after it was
+	// logged, DefaultDedupWindow when zero. In Consecutive mode without a
+	// Window duplicates are swallowed until a different entry is logged or
+	// until Flush.
+	Window time.Duration
+	// Consecutive only swallows duplicates logged one after the other, any
+	// different entry closes the window early.
+	Consecutive bool
+	// Fields are the keys compared along with the level and message to
+	// tell whether two entries are duplicates.
+	Fields []string
+}
+
+// Deduper swallows repeated entries. The first entry is logged as usual,
+// its duplicates are counted and, once the window closes, a summary entry
+// with the same level, message and fields is logged with the number of
+// swallowed duplicates in the `repeated` field and the times of the first
+// and last ones in `first_time` and `last_time`. Summaries go to the Out and
+// the hooks of the logger like any other entry. Entries at PanicLevel and
+// FatalLevel are never deduplicated.
+//
+// A Deduper is safe for concurrent use and can be shared between loggers.
+type Deduper struct {
+	opts DedupOptions
+
+	mu      sync.Mutex
+	groups  map[string]*dedupGroup
+	current string
+
+	suppressed uint64
+}
+
+// dedupGroup holds an entry and the duplicates swallowed since.
+type dedupGroup struct {
+	entry       *Entry
+	count       int
+	first, last time.Time
+	timer       *time.Timer
+}
+
+// NewDeduper returns a Deduper with the given options.
+func NewDeduper(opts DedupOptions) *Deduper {
+	if opts.Window <= 0 && !opts.Consecutive {
+		// Every distinct entry is kept until its window closes, there must
+		// be one.
+		opts.Window = DefaultDedupWindow
+	}
+	return &Deduper{
+		opts:   opts,
+		groups: make(map[string]*dedupGroup),
+	}
+}
+
+// Suppressed returns the number of duplicates swallowed so far.
+func (d *Deduper) Suppressed() uint64 {
+	return atomic.LoadUint64(&d.suppressed)
+}
+
+// Flush closes all the windows and logs their summaries.
+func (d *Deduper) Flush() {
+	d.mu.Lock()
+	groups := make([]*dedupGroup, 0, len(d.groups))
+	for key, group := range d.groups {
+		groups = append(groups, d.close(key, group))
+	}
+	d.mu.Unlock()
+	for _, group := range groups {
+		group.summarize()
+	}
+}
+
+// allow reports whether the entry should be logged, it is swallowed
+// otherwise. In Consecutive mode the summary of the previous entry is
+// logged first when the entry differs from it.
+func (d *Deduper) allow(entry *Entry) bool {
+	if entry.Level <= FatalLevel {
+		return true
+	}
+	key := d.key(entry)
+
+	d.mu.Lock()
+	if group, ok := d.groups[key]; ok {
+		group.count++
+		group.last = entry.Time
+		d.mu.Unlock()
+		atomic.AddUint64(&d.suppressed, 1)
+		return false
+	}
+	var previous *dedupGroup
+	if d.opts.Consecutive {
+		if group, ok := d.groups[d.current]; ok {
+			previous = d.close(d.current, group)
+		}
+		d.current = key
+	}
+	group := &dedupGroup{entry: entry.snapshot(), first: entry.Time, last: entry.Time}
+	if d.opts.Window > 0 {
+		group.timer = time.AfterFunc(d.opts.Window, func() { d.expire(key, group) })
+	}
+	d.groups[key] = group
+	d.mu.Unlock()
+
+	if previous != nil {
+		previous.summarize()
+	}
+	return true
+}
+
+func (d *Deduper) expire(key string, group *dedupGroup) {
+	d.mu.Lock()
+	if d.groups[key] != group {
+		// already closed
+		d.mu.Unlock()
+		return
+	}
+	d.close(key, group)
+	d.mu.Unlock()
+	group.summarize()
+}
+
+// close removes the group, it must be called with d.mu held.
+func (d *Deduper) close(key string, group *dedupGroup) *dedupGroup {
+	if group.timer != nil {
+		group.timer.Stop()
+	}
+	delete(d.groups, key)
+	return group
+}
+
+// key identifies the duplicates of the entry.
+func (d *Deduper) key(entry *Entry) string {
+	var b strings.Builder
+	b.WriteString(entry.Level.String())
+	b.WriteByte(0)
+	b.WriteString(entry.Logger.name)
+	b.WriteByte(0)
+	b.WriteString(entry.Message)
+	for _, k := range d.opts.Fields {
+		b.WriteByte(0)
+		if i := fieldIndex(entry.fields, k); i >= 0 {
+			fmt.Fprint(&b, entry.fields[i].Value())
+		} else if v, ok := entry.Data[k]; ok {
+			fmt.Fprint(&b, v)
+		}
+	}
+	return b.String()
+}
+
+// summarize logs the summary of the group, if anything was swallowed.
+func (group *dedupGroup) summarize() {
+	if group.count == 0 {
+		return
+	}
+	summary := group.entry.With(
+		Int(FieldKeyRepeated, group.count),
+		Time(FieldKeyFirstTime, group.first),
+		Time(FieldKeyLastTime, group.last),
+	)
+	summary.Time = group.last
+	summary.Level = group.entry.Level
+	summary.Message = group.entry.Message
+	summary.Caller = group.entry.Caller
+
+	logger := summary.Logger
+	logger.lock().Lock()
+	bufPool := summary.getBufferPool()
+	logger.lock().Unlock()
+	summary.output(bufPool)
+}
+
+// snapshot copies the entry before hooks get to modify it.
+func (entry *Entry) snapshot() *Entry {
+	dup := entry.Dup()
+	dup.Level = entry.Level
+	dup.Message = entry.Message
+	dup.Caller = entry.Caller
+	return dup
+}
+
+// SetDeduper sets the deduper of the logger, nil disables deduplication.
+func (logger *Logger) SetDeduper(deduper *Deduper) {
+	logger.lock().Lock()
+	logger.deduper = deduper
+	logger.lock().Unlock()
+	logger.eachChild(func(child *Logger) {
+		child.SetDeduper(deduper)
+	})
+}
@@ -0,0 +1,128 @@
+package hlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeLines(t *testing.T, buffer *bytes.Buffer) []Fields {
+	var lines []Fields
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		var fields Fields
+		require.NoError(t, json.Unmarshal([]byte(line), &fields), line)
+		lines = append(lines, fields)
+	}
+	return lines
+}
+
+func TestDeduperConsecutive(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+	deduper := NewDeduper(DedupOptions{Consecutive: true, Fields: []string{"host"}})
+	logger.SetDeduper(deduper)
+
+	for i := 0; i < 3; i++ {
+		logger.WithField("host", "db1").WithField("attempt", i).Error("connection refused")
+	}
+	logger.WithField("host", "db2").Error("connection refused")
+
+	lines := decodeLines(t, &buffer)
+	require.Len(t, lines, 3)
+	assert.Equal(t, "db1", lines[0]["host"])
+	assert.Nil(t, lines[0][FieldKeyRepeated])
+
+	summary := lines[1]
+	assert.Equal(t, "connection refused", summary["msg"])
+	assert.Equal(t, "error", summary["level"])
+	assert.Equal(t, "db1", summary["host"])
+	assert.Equal(t, float64(0), summary["attempt"])
+	assert.Equal(t, float64(2), summary[FieldKeyRepeated])
+	assert.NotEmpty(t, summary[FieldKeyFirstTime])
+	assert.NotEmpty(t, summary[FieldKeyLastTime])
+
+	assert.Equal(t, "db2", lines[2]["host"])
+	assert.Equal(t, uint64(2), deduper.Suppressed())
+}
+
+func TestDeduperWindow(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+	hook := new(fieldsHook)
+	logger.AddHook(hook)
+	logger.SetDeduper(NewDeduper(DedupOptions{Window: 20 * time.Millisecond}))
+
+	logger.Error("timeout")
+	logger.Warn("slow")
+	logger.Error("timeout")
+	logger.Error("timeout")
+
+	assert.Eventually(t, func() bool {
+		logger.lock().Lock()
+		defer logger.lock().Unlock()
+		return strings.Count(buffer.String(), "\n") == 3
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int64(2), hook.data[FieldKeyRepeated])
+}
+
+func TestDeduperFlush(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+	deduper := NewDeduper(DedupOptions{})
+	logger.SetDeduper(deduper)
+
+	logger.Info("same")
+	logger.Info("same")
+	deduper.Flush()
+	deduper.Flush()
+	logger.Info("same")
+
+	lines := decodeLines(t, &buffer)
+	require.Len(t, lines, 3)
+	assert.Equal(t, float64(1), lines[1][FieldKeyRepeated])
+	assert.Nil(t, lines[2][FieldKeyRepeated])
+}
+
+func TestDeduperDefaultWindow(t *testing.T) {
+	window := DefaultDedupWindow
+	DefaultDedupWindow = 20 * time.Millisecond
+	defer func() { DefaultDedupWindow = window }()
+
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+	deduper := NewDeduper(DedupOptions{})
+	logger.SetDeduper(deduper)
+
+	for i := 0; i < 10; i++ {
+		logger.Infof("distinct %d", i)
+	}
+	logger.Info("distinct 0")
+
+	// the windows close and the groups are released without a Flush
+	assert.Eventually(t, func() bool {
+		logger.lock().Lock()
+		defer logger.lock().Unlock()
+		return strings.Count(buffer.String(), "\n") == 11
+	}, time.Second, 5*time.Millisecond)
+	deduper.mu.Lock()
+	assert.Empty(t, deduper.groups)
+	deduper.mu.Unlock()
+	lines := decodeLines(t, &buffer)
+	require.Len(t, lines, 11)
+	assert.Equal(t, float64(1), lines[10][FieldKeyRepeated])
+
+	assert.Zero(t, NewDeduper(DedupOptions{Consecutive: true}).opts.Window)
+}
@@ -8,29 +8,9 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
-	"sync"
 	"time"
 )
 
-var (
-	// qualified package name, cached at first use
-	logPackage string
-	// Positions in the call stack when tracing to report the calling method
-	minimumCallerDepth int
-	// Used for caller information initialisation
-	callerInitOnce sync.Once
-)
-
-const (
-	maximumCallerDepth int = 25
-	knownHlogFrames    int = 4
-)
-
-func init() {
-	// start at the bottom of the stack before the package-name cache is primed
-	minimumCallerDepth = 1
-}
-
 // ErrorKey Defines the key when adding errors using WithError.
 var ErrorKey = "error"
 
@@ -47,7 +27,8 @@ type Entry struct {
 	// Level the log entry was logged at: Trace, Debug, Info, Warn, Error, Fatal or Panic
 	// This field will be set on entry firing and the value will be equal to the one in Logger struct field.
 	Level Level
-	// Calling method, with package name
+	// Calling method, with package name. The frame is shared by the entries
+	// logged from the same place and must not be modified.
 	Caller *runtime.Frame
 	// Message passed to Trace, Debug, Info, Warn, Error, Fatal or Panic
 	Message string
@@ -57,6 +38,14 @@ type Entry struct {
 	Context context.Context
 	// err may contain a field formatting error
 	err string
+	// fields holds the typed fields added with With, they shadow Data keys
+	fields []Field
+	// callerSkip is the number of frames skipped on top of the ones of the
+	// logger when reporting the caller, see WithCallerSkip
+	callerSkip int
+	// caller is the caller when it is known beforehand, as for slog records
+	// and standard library log lines
+	caller *runtime.Frame
 }
 
 func NewEntry(logger *Logger) *Entry {
@@ -68,11 +57,40 @@ func NewEntry(logger *Logger) *Entry {
 }
 
 func (entry *Entry) Dup() *Entry {
-	data := make(Fields, len(entry.Data))
+	return entry.dupTo(&Entry{Data: make(Fields, len(entry.Data))})
+}
+
+// dupTo copies the entry to dup, whose Data map must be empty, and returns
+// it.
+func (entry *Entry) dupTo(dup *Entry) *Entry {
 	for k, v := range entry.Data {
-		data[k] = v
+		dup.Data[k] = v
 	}
-	return &Entry{Logger: entry.Logger, Data: data, Time: entry.Time, Context: entry.Context, err: entry.err}
+	dup.Logger = entry.Logger
+	dup.Time = entry.Time
+	dup.Context = entry.Context
+	dup.err = entry.err
+	dup.fields = entry.fields
+	dup.callerSkip = entry.callerSkip
+	dup.caller = entry.caller
+	return dup
+}
+
+// maxPooledData is the size above which the Data map of a released entry is
+// dropped instead of being reused.
+const maxPooledData = 64
+
+// reset clears the entry so that it can be reused, keeping its Data map.
+func (entry *Entry) reset() {
+	data := entry.Data
+	if len(data) > maxPooledData {
+		data = make(Fields, 6)
+	} else {
+		for k := range data {
+			delete(data, k)
+		}
+	}
+	*entry = Entry{Logger: entry.Logger, Data: data}
 }
 
 // Bytes Returns the bytes' representation of this entry from the formatter.
@@ -102,7 +120,7 @@ func (entry *Entry) WithContext(ctx context.Context) *Entry {
 	for k, v := range entry.Data {
 		dataCopy[k] = v
 	}
-	return &Entry{Logger: entry.Logger, Data: dataCopy, Time: entry.Time, err: entry.err, Context: ctx}
+	return &Entry{Logger: entry.Logger, Data: dataCopy, Time: entry.Time, err: entry.err, Context: ctx, fields: entry.fields, callerSkip: entry.callerSkip}
 }
 
 // WithField Add a single field to the Entry.
@@ -110,7 +128,8 @@ func (entry *Entry) WithField(key string, value interface{}) *Entry {
 	return entry.WithFields(Fields{key: value})
 }
 
-// WithFields Add a map of fields to the Entry.
+// WithFields Add a map of fields to the Entry. The Data map is copied, even
+// when the entry isn't logged, use With on hot paths.
 func (entry *Entry) WithFields(fields Fields) *Entry {
 	data := make(Fields, len(entry.Data)+len(fields))
 	for k, v := range entry.Data {
@@ -119,7 +138,9 @@ func (entry *Entry) WithFields(fields Fields) *Entry {
 	fieldErr := entry.err
 	for k, v := range fields {
 		isErrField := false
-		if t := reflect.TypeOf(v); t != nil {
+		// Valuers such as Lazy are funcs resolved when the entry is logged.
+		_, lazy := v.(Valuer)
+		if t := reflect.TypeOf(v); t != nil && !lazy {
 			switch {
 			case t.Kind() == reflect.Func, t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Func:
 				isErrField = true
@@ -136,7 +157,37 @@ func (entry *Entry) WithFields(fields Fields) *Entry {
 			data[k] = v
 		}
 	}
-	return &Entry{Logger: entry.Logger, Data: data, Time: entry.Time, err: fieldErr, Context: entry.Context}
+	return &Entry{Logger: entry.Logger, Data: data, Time: entry.Time, err: fieldErr, Context: entry.Context, fields: fieldsWithout(entry.fields, fields), callerSkip: entry.callerSkip}
+}
+
+// With Add typed fields to the Entry. The Data map is shared with the
+// returned Entry instead of being copied, so this is the cheaper way to build
+// up entries on hot paths. A typed field replaces any field with the same key.
+func (entry *Entry) With(fields ...Field) *Entry {
+	merged := make([]Field, len(entry.fields), len(entry.fields)+len(fields))
+	copy(merged, entry.fields)
+	for _, f := range fields {
+		if i := fieldIndex(merged, f.key); i >= 0 {
+			merged[i] = f
+		} else {
+			merged = append(merged, f)
+		}
+	}
+	return &Entry{Logger: entry.Logger, Data: entry.Data, Time: entry.Time, err: entry.err, Context: entry.Context, fields: merged, callerSkip: entry.callerSkip}
+}
+
+// Fields returns the typed fields added to the Entry with With.
+func (entry *Entry) Fields() []Field {
+	return entry.fields
+}
+
+// mergeFields boxes the typed fields into Data. It must only be called on
+// entries owning their Data map, as done in log.
+func (entry *Entry) mergeFields() {
+	for i := range entry.fields {
+		entry.Data[entry.fields[i].key] = entry.fields[i].Value()
+	}
+	entry.fields = nil
 }
 
 // WithTime Overrides the time of the Entry.
@@ -145,7 +196,7 @@ func (entry *Entry) WithTime(t time.Time) *Entry {
 	for k, v := range entry.Data {
 		dataCopy[k] = v
 	}
-	return &Entry{Logger: entry.Logger, Data: dataCopy, Time: t, err: entry.err, Context: entry.Context}
+	return &Entry{Logger: entry.Logger, Data: dataCopy, Time: t, err: entry.err, Context: entry.Context, fields: entry.fields, callerSkip: entry.callerSkip}
 }
 
 // getPackageName reduces a fully qualified function name to the package name
@@ -163,35 +214,13 @@ func getPackageName(f string) string {
 	return f
 }
 
-// getCaller retrieves the name of the first non-hlog calling function
-func getCaller() *runtime.Frame {
-	// cache this package's fully-qualified name
-	callerInitOnce.Do(func() {
-		pcs := make([]uintptr, maximumCallerDepth)
-		_ = runtime.Callers(0, pcs)
-		// dynamic get the package name and the minimum caller depth
-		for i := 0; i < maximumCallerDepth; i++ {
-			funcName := runtime.FuncForPC(pcs[i]).Name()
-			if strings.Contains(funcName, "getCaller") {
-				logPackage = getPackageName(funcName)
-				break
-			}
-		}
-		minimumCallerDepth = knownHlogFrames
-	})
-	// Restrict the look back frames to avoid runaway lookups
-	pcs := make([]uintptr, maximumCallerDepth)
-	depth := runtime.Callers(minimumCallerDepth, pcs)
-	frames := runtime.CallersFrames(pcs[:depth])
-	for f, again := frames.Next(); again; f, again = frames.Next() {
-		pkg := getPackageName(f.Function)
-		// If the caller isn't part of this package, we're done
-		if pkg != logPackage {
-			return &f //nolint:scopelint
-		}
+// LoggerName returns the name of the named logger the entry belongs to, or
+// an empty string.
+func (entry *Entry) LoggerName() string {
+	if entry.Logger == nil {
+		return ""
 	}
-	// if we got here, we failed to find the caller's context
-	return nil
+	return entry.Logger.name
 }
 
 func (entry Entry) HasCaller() (has bool) {
@@ -201,38 +230,100 @@ func (entry Entry) HasCaller() (has bool) {
 }
 
 func (entry *Entry) log(level Level, msg string) {
-	var buffer *bytes.Buffer
-	newEntry := entry.Dup()
-	if newEntry.Time.IsZero() {
-		newEntry.Time = time.Now()
+	newEntry := entry.emit(level, msg)
+
+	// To avoid Entry#log() returning a value that only would make sense for
+	// panic() to use in Entry#Panic(), we avoid the allocation by checking
+	// directly here.
+	if newEntry != nil && level <= PanicLevel {
+		panic(newEntry)
+	}
+}
+
+// emit logs the entry at the given level without panicking. It returns the
+// logged entry, or nil when it was dropped or when nothing kept it and it was
+// put back in the entry pool of the logger.
+func (entry *Entry) emit(level Level, msg string) *Entry {
+	entry.Logger.lock().Lock()
+	reportCaller := entry.Logger.ReportCaller
+	callerSkip := entry.Logger.callerSkip
+	redactor := entry.Logger.redactor
+	processors := entry.Logger.processors
+	bufPool := entry.getBufferPool()
+	sampler := entry.Logger.sampler
+	deduper := entry.Logger.deduper
+	extractors := entry.Logger.extractors
+	reportStack := entry.Logger.reportStack && level <= entry.Logger.stackLevel
+	entry.Logger.lock().Unlock()
+
+	t := entry.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	// Sampled out entries are dropped before any copy, hook or formatting.
+	if sampler != nil && !sampler.allow(level, entry.Logger.name, msg, t) {
+		return nil
 	}
+
+	// The copy is only handed out to hooks, processors and panics, otherwise
+	// it goes back to the pool once written.
+	newEntry := entry.dupTo(entry.Logger.newEntry())
+	newEntry.Time = t
 	newEntry.Level = level
 	newEntry.Message = msg
-	newEntry.Logger.mu.Lock()
-	reportCaller := newEntry.Logger.ReportCaller
-	bufPool := newEntry.getBufferPool()
-	newEntry.Logger.mu.Unlock()
+	if newEntry.Context != nil {
+		newEntry.mergeContext()
+		newEntry.mergeTrace()
+		newEntry.extractContext(extractors)
+	}
 	if reportCaller {
-		newEntry.Caller = getCaller()
+		if newEntry.caller != nil {
+			newEntry.Caller = newEntry.caller
+		} else {
+			newEntry.Caller = getCaller(callerSkip + newEntry.callerSkip)
+		}
+	}
+	newEntry.resolveValuers()
+	if len(processors) > 0 {
+		if newEntry = newEntry.process(processors); newEntry == nil {
+			return nil
+		}
+	}
+	// No hook nor output may see what the redactor masks.
+	if redactor != nil {
+		newEntry.redact(redactor)
+	}
+	if deduper != nil && !deduper.allow(newEntry) {
+		if len(processors) == 0 {
+			entry.Logger.releaseEntry(newEntry)
+		}
+		return nil
+	}
+	if reportStack {
+		newEntry.addStack()
 	}
-	newEntry.fireHooks()
-	buffer = bufPool.Get()
+	if hooked := newEntry.output(bufPool); hooked || len(processors) > 0 || level <= PanicLevel {
+		return newEntry
+	}
+	entry.Logger.releaseEntry(newEntry)
+	return nil
+}
+
+// output fires the hooks and writes the entry to the logger output. It
+// reports whether the entry was handed to hooks.
+func (entry *Entry) output(bufPool BufferPool) bool {
+	hooked := entry.fireHooks()
+	buffer := bufPool.Get()
 	defer func() {
-		newEntry.Buffer = nil
+		entry.Buffer = nil
 		buffer.Reset()
 		bufPool.Put(buffer)
 	}()
 	buffer.Reset()
-	newEntry.Buffer = buffer
-	newEntry.write()
-	newEntry.Buffer = nil
-
-	// To avoid Entry#log() returning a value that only would make sense for
-	// panic() to use in Entry#Panic(), we avoid the allocation by checking
-	// directly here.
-	if level <= PanicLevel {
-		panic(newEntry)
-	}
+	entry.Buffer = buffer
+	entry.write()
+	entry.Buffer = nil
+	return hooked
 }
 
 func (entry *Entry) getBufferPool() (pool BufferPool) {
@@ -242,19 +333,45 @@ func (entry *Entry) getBufferPool() (pool BufferPool) {
 	return bufferPool
 }
 
-func (entry *Entry) fireHooks() {
-	var tmpHooks LevelHooks
-	entry.Logger.mu.Lock()
-	tmpHooks = make(LevelHooks, len(entry.Logger.Hooks))
-	for k, v := range entry.Logger.Hooks {
-		tmpHooks[k] = v
+// fireHooks fires the hooks of the level of the entry and reports whether
+// there were any.
+func (entry *Entry) fireHooks() bool {
+	logger := entry.Logger
+	logger.lock().Lock()
+	hooks := logger.Hooks[entry.Level]
+	policy := logger.hookPolicy
+	handler := logger.ErrorHandler
+	logger.lock().Unlock()
+	if len(hooks) == 0 {
+		return false
 	}
-	entry.Logger.mu.Unlock()
 
-	err := tmpHooks.Fire(entry.Level, entry)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to fire hooks: %v\n", err)
+	// Hooks only know about Data, so give them the typed fields too.
+	if len(entry.fields) > 0 {
+		entry.mergeFields()
+	}
+
+	var errs HookErrors
+	breaker := logger.hookBreaker()
+	now := time.Now()
+	for _, hook := range hooks {
+		if policy.DisableAfter > 0 && breaker.disabled(hook, now) {
+			continue
+		}
+		if err := hook.Fire(entry); err != nil {
+			errs = append(errs, &HookError{Hook: hook, Err: err})
+			breaker.failure(hook, policy, now)
+			if policy.StopOnError {
+				break
+			}
+		} else if policy.DisableAfter > 0 {
+			breaker.success(hook)
+		}
+	}
+	if len(errs) > 0 {
+		entry.handleHookErrors(handler, errs)
 	}
+	return true
 }
 
 func (entry *Entry) write() {
@@ -263,8 +380,8 @@ func (entry *Entry) write() {
 		fmt.Fprintf(os.Stderr, "Failed to obtain reader, %v\n", err)
 		return
 	}
-	entry.Logger.mu.Lock()
-	defer entry.Logger.mu.Unlock()
+	entry.Logger.lock().Lock()
+	defer entry.Logger.lock().Unlock()
 	if _, err := entry.Logger.Out.Write(serialized); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
 	}
@@ -272,7 +389,7 @@ func (entry *Entry) write() {
 
 func (entry *Entry) Log(level Level, args ...interface{}) {
 	if entry.Logger.IsLevelEnabled(level) {
-		entry.log(level, fmt.Sprint(args...))
+		entry.log(level, sprint(args))
 	}
 }
 
@@ -316,7 +433,7 @@ func (entry *Entry) Panic(args ...interface{}) {
 // Logf Entry Printf family functions
 func (entry *Entry) Logf(level Level, format string, args ...interface{}) {
 	if entry.Logger.IsLevelEnabled(level) {
-		entry.Log(level, fmt.Sprintf(format, args...))
+		entry.log(level, fmt.Sprintf(format, args...))
 	}
 }
 
@@ -361,7 +478,7 @@ func (entry *Entry) Panicf(format string, args ...interface{}) {
 
 func (entry *Entry) Logln(level Level, args ...interface{}) {
 	if entry.Logger.IsLevelEnabled(level) {
-		entry.Log(level, entry.sprintLn(args...))
+		entry.log(level, entry.sprintLn(args...))
 	}
 }
 
@@ -402,6 +519,16 @@ func (entry *Entry) Panicln(args ...interface{}) {
 	entry.Logln(PanicLevel, args...)
 }
 
+// sprint is fmt.Sprint, without copying a lone string argument.
+func sprint(args []interface{}) string {
+	if len(args) == 1 {
+		if s, ok := args[0].(string); ok {
+			return s
+		}
+	}
+	return fmt.Sprint(args...)
+}
+
 // Sprintlnn => Sprint no newline. This is to get the behavior of how
 // fmt.Sprintln where spaces are always added between operands, regardless of
 // their type. Instead of vendoring the Sprintln implementation to spare a
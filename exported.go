@@ -51,11 +51,27 @@ func AddHook(hook Hook) {
 	std.AddHook(hook)
 }
 
+// AddContextExtractor adds a context extractor to the standard logger.
+func AddContextExtractor(extractor ContextExtractor) {
+	std.AddContextExtractor(extractor)
+}
+
+// AddProcessor adds a processor to the standard logger.
+func AddProcessor(processor Processor) {
+	std.AddProcessor(processor)
+}
+
 // WithError creates an entry from the standard logger and adds an error to it, using the value defined in ErrorKey as key.
 func WithError(err error) *Entry {
 	return std.WithField(ErrorKey, err)
 }
 
+// WithStack creates an entry from the standard logger and adds the stack of
+// the caller to it.
+func WithStack() *Entry {
+	return std.WithStack()
+}
+
 // WithContext creates an entry from the standard logger and adds a context to it.
 func WithContext(ctx context.Context) *Entry {
 	return std.WithContext(ctx)
@@ -80,6 +96,15 @@ func WithFields(fields Fields) *Entry {
 	return std.WithFields(fields)
 }
 
+// With creates an entry from the standard logger and adds typed fields to
+// it. It avoids the map copy done by `WithFields`.
+//
+// Note that it doesn't log until you call Debug, Print, Info, Warn, Fatal
+// or Panic on the Entry it returns.
+func With(fields ...Field) *Entry {
+	return std.With(fields...)
+}
+
 // WithTime creates an entry from the standard logger and overrides the time of
 // logs generated with it.
 //
@@ -0,0 +1,234 @@
+package hlog
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Field is a typed key/value pair that can be attached to an Entry with
+// `With`. Unlike `WithFields`, adding typed fields neither copies the Fields
+// map nor boxes the value, and the default formatters encode them directly.
+type Field struct {
+	kind           FieldKind
+	key            string
+	stringValue    string
+	stringsValue   []string
+	intValue       int64
+	interfaceValue interface{}
+}
+
+// FieldKind describes how the value of a Field is stored and encoded.
+type FieldKind int
+
+const (
+	StringField FieldKind = iota + 1
+	StringsField
+	IntField
+	UintField
+	FloatField
+	BoolField
+	DurationField
+	TimeField
+	ByteStringField
+	ErrorField
+	InterfaceField
+)
+
+// Any constructs a Field holding an arbitrary value. It boxes the value, use
+// one of the typed constructors when possible.
+func Any(key string, value interface{}) Field {
+	return Field{kind: InterfaceField, key: key, interfaceValue: value}
+}
+
+// Str constructs a Field holding a string.
+func Str(key, value string) Field {
+	return Field{kind: StringField, key: key, stringValue: value}
+}
+
+// Strs constructs a Field holding a slice of strings.
+func Strs(key string, value []string) Field {
+	return Field{kind: StringsField, key: key, stringsValue: value}
+}
+
+// ByteString constructs a Field holding UTF-8 encoded text as bytes.
+func ByteString(key string, value []byte) Field {
+	return Field{kind: ByteStringField, key: key, stringValue: string(value)}
+}
+
+// Int constructs a Field holding an int.
+func Int(key string, value int) Field {
+	return Field{kind: IntField, key: key, intValue: int64(value)}
+}
+
+// Int64 constructs a Field holding an int64.
+func Int64(key string, value int64) Field {
+	return Field{kind: IntField, key: key, intValue: value}
+}
+
+// Uint64 constructs a Field holding an uint64.
+func Uint64(key string, value uint64) Field {
+	return Field{kind: UintField, key: key, intValue: int64(value)}
+}
+
+// Float64 constructs a Field holding a float64.
+func Float64(key string, value float64) Field {
+	return Field{kind: FloatField, key: key, intValue: int64(math.Float64bits(value))}
+}
+
+// Bool constructs a Field holding a bool.
+func Bool(key string, value bool) Field {
+	var integer int64
+	if value {
+		integer = 1
+	}
+	return Field{kind: BoolField, key: key, intValue: integer}
+}
+
+// Dur constructs a Field holding a time.Duration. It is encoded in its
+// string form, e.g. "1.5s".
+func Dur(key string, value time.Duration) Field {
+	return Field{kind: DurationField, key: key, intValue: int64(value)}
+}
+
+// Time constructs a Field holding a time.Time. It is encoded using
+// time.RFC3339Nano.
+func Time(key string, value time.Time) Field {
+	if sec := value.Unix(); sec <= math.MinInt64/int64(time.Second) || sec >= math.MaxInt64/int64(time.Second) {
+		// UnixNano only covers the years 1678 to 2262, the times out of
+		// that range, like the zero time, are boxed instead.
+		return Field{kind: TimeField, key: key, interfaceValue: value.Round(0)}
+	}
+	return Field{kind: TimeField, key: key, intValue: value.UnixNano(), interfaceValue: value.Location()}
+}
+
+// Err constructs a Field holding an error, using the key defined in ErrorKey.
+func Err(err error) Field {
+	return NamedErr(ErrorKey, err)
+}
+
+// NamedErr constructs a Field holding an error under the given key.
+func NamedErr(key string, err error) Field {
+	return Field{kind: ErrorField, key: key, interfaceValue: err}
+}
+
+// Key returns the key of the field.
+func (f Field) Key() string {
+	return f.key
+}
+
+// Kind returns the kind of the field.
+func (f Field) Kind() FieldKind {
+	return f.kind
+}
+
+// Value returns the value of the field boxed in an interface, the same way
+// it would have been stored with `WithField`.
+func (f Field) Value() interface{} {
+	switch f.kind {
+	case StringField, ByteStringField:
+		return f.stringValue
+	case StringsField:
+		return f.stringsValue
+	case IntField:
+		return f.intValue
+	case UintField:
+		return uint64(f.intValue)
+	case FloatField:
+		return math.Float64frombits(uint64(f.intValue))
+	case BoolField:
+		return f.intValue != 0
+	case DurationField:
+		return time.Duration(f.intValue)
+	case TimeField:
+		return f.time()
+	default:
+		return f.interfaceValue
+	}
+}
+
+func (f Field) time() time.Time {
+	if t, ok := f.interfaceValue.(time.Time); ok {
+		return t
+	}
+	t := time.Unix(0, f.intValue)
+	if loc, ok := f.interfaceValue.(*time.Location); ok {
+		t = t.In(loc)
+	}
+	return t
+}
+
+// text returns the value of the string-like kinds, the second value reports
+// whether the field is such a kind.
+func (f Field) text() (string, bool) {
+	switch f.kind {
+	case StringField, ByteStringField:
+		return f.stringValue, true
+	case ErrorField:
+		if err, ok := f.interfaceValue.(error); ok && err != nil {
+			return err.Error(), true
+		}
+	}
+	return "", false
+}
+
+// appendText writes the plain text form of the field value, as used by the
+// TextFormatter, to b.
+func (f Field) appendText(b *bytes.Buffer) {
+	var scratch [64]byte
+	switch f.kind {
+	case StringField, ByteStringField:
+		b.WriteString(f.stringValue)
+	case IntField:
+		b.Write(strconv.AppendInt(scratch[:0], f.intValue, 10))
+	case UintField:
+		b.Write(strconv.AppendUint(scratch[:0], uint64(f.intValue), 10))
+	case FloatField:
+		b.Write(strconv.AppendFloat(scratch[:0], math.Float64frombits(uint64(f.intValue)), 'g', -1, 64))
+	case BoolField:
+		b.Write(strconv.AppendBool(scratch[:0], f.intValue != 0))
+	case DurationField:
+		b.WriteString(time.Duration(f.intValue).String())
+	case TimeField:
+		b.Write(f.time().AppendFormat(scratch[:0], time.RFC3339Nano))
+	default:
+		fmt.Fprint(b, f.Value())
+	}
+}
+
+// fieldIndex returns the position of the field with the given key, or -1.
+func fieldIndex(fields []Field, key string) int {
+	for i := range fields {
+		if fields[i].key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// fieldsWithout returns fields minus the ones whose key is in data. The
+// original slice is returned untouched when nothing has to be removed.
+func fieldsWithout(fields []Field, data Fields) []Field {
+	if len(fields) == 0 || len(data) == 0 {
+		return fields
+	}
+	var out []Field
+	for i := range fields {
+		if _, ok := data[fields[i].key]; ok {
+			if out == nil {
+				out = make([]Field, i, len(fields))
+				copy(out, fields[:i])
+			}
+			continue
+		}
+		if out != nil {
+			out = append(out, fields[i])
+		}
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
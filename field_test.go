@@ -0,0 +1,127 @@
+package hlog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTypedFieldsJSON(t *testing.T) {
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.With(
+			Str("str", "<b>"),
+			Int("int", -3),
+			Uint64("uint", 7),
+			Float64("float", 1.5),
+			Bool("bool", true),
+			Dur("dur", 1500*time.Millisecond),
+			Strs("strs", []string{"a", "b"}),
+			Err(errors.New("wrong")),
+			Any("any", map[string]int{"x": 1}),
+		).Info("typed")
+	}, func(fields Fields) {
+		assert.Equal(t, "typed", fields["msg"])
+		assert.Equal(t, "<b>", fields["str"])
+		assert.Equal(t, -3.0, fields["int"])
+		assert.Equal(t, 7.0, fields["uint"])
+		assert.Equal(t, 1.5, fields["float"])
+		assert.Equal(t, true, fields["bool"])
+		assert.Equal(t, "1.5s", fields["dur"])
+		assert.Equal(t, []interface{}{"a", "b"}, fields["strs"])
+		assert.Equal(t, "wrong", fields["error"])
+		assert.Equal(t, map[string]interface{}{"x": 1.0}, fields["any"])
+	})
+}
+
+func TestWithTypedFieldsShadowData(t *testing.T) {
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.WithField("a", "map").With(Str("a", "typed"), Int("b", 1)).WithField("b", "map").Info("")
+	}, func(fields Fields) {
+		assert.Equal(t, "typed", fields["a"])
+		assert.Equal(t, "map", fields["b"])
+	})
+}
+
+func TestWithTypedFieldsClash(t *testing.T) {
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.With(Str("msg", "clash")).Info("hello")
+	}, func(fields Fields) {
+		assert.Equal(t, "hello", fields["msg"])
+		assert.Equal(t, "clash", fields["fields.msg"])
+	})
+}
+
+func TestWithTypedFieldsDataKey(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &JSONFormatter{DataKey: "data", DisableTimestamp: true}
+
+	logger.WithField("a", 1).With(Int("b", 2)).Info("nested")
+
+	assert.Equal(t, `{"data":{"a":1,"b":2},"level":"info","msg":"nested"}`+"\n", buffer.String())
+}
+
+func TestWithTypedFieldsText(t *testing.T) {
+	LogAndAssertText(t, func(log *Logger) {
+		log.With(Str("str", "x:y"), Int("int", 42), Bool("bool", false)).Info("typed")
+	}, func(fields map[string]string) {
+		assert.Equal(t, "typed", fields["msg"])
+		assert.Equal(t, "x:y", fields["str"])
+		assert.Equal(t, "42", fields["int"])
+		assert.Equal(t, "false", fields["bool"])
+	})
+}
+
+func TestWithTypedFieldsHooks(t *testing.T) {
+	hook := &fieldsHook{}
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.AddHook(hook)
+
+	logger.With(Int("n", 3)).Info("hooked")
+
+	assert.Equal(t, int64(3), hook.data["n"])
+}
+
+type fieldsHook struct {
+	data Fields
+}
+
+func (h *fieldsHook) Levels() []Level {
+	return AllLevels
+}
+
+func (h *fieldsHook) Fire(entry *Entry) error {
+	h.data = entry.Data
+	return nil
+}
+
+func TestTimeFieldRange(t *testing.T) {
+	cet := time.FixedZone("CET", 3600)
+	for _, value := range []time.Time{
+		{},
+		time.Date(9999, 12, 31, 23, 59, 59, 999999999, time.UTC),
+		time.Date(1200, 1, 2, 3, 4, 5, 6, cet),
+		time.Date(2024, 1, 2, 3, 4, 5, 6, cet),
+	} {
+		want := value.Format(time.RFC3339Nano)
+		f := Time("t", value)
+		assert.True(t, value.Equal(f.Value().(time.Time)), want)
+		assert.Equal(t, want, f.Value().(time.Time).Format(time.RFC3339Nano))
+
+		LogAndAssertJSON(t, func(log *Logger) {
+			log.With(f).Info("")
+		}, func(fields Fields) {
+			assert.Equal(t, want, fields["t"])
+		})
+		LogAndAssertText(t, func(log *Logger) {
+			log.With(f).Info("")
+		}, func(fields map[string]string) {
+			assert.Equal(t, want, fields["t"])
+		})
+	}
+}
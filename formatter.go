@@ -39,55 +39,70 @@ type Formatter interface {
 //
 // It's not exported because it's still using Data in an opinionated way. It's to
 // avoid code duplication between the two default formatters.
-func prefixFieldClashes(data Fields, fieldMap FieldMap, reportCaller bool) {
-	timeKey := fieldMap.resolve(FieldKeyTime)
-	if t, ok := data[timeKey]; ok {
-		data["fields."+timeKey] = t
-		delete(data, timeKey)
-	}
-
-	msgKey := fieldMap.resolve(FieldKeyMsg)
-	if m, ok := data[msgKey]; ok {
-		data["fields."+msgKey] = m
-		delete(data, msgKey)
-	}
-
-	levelKey := fieldMap.resolve(FieldKeyLevel)
-	if l, ok := data[levelKey]; ok {
-		data["fields."+levelKey] = l
-		delete(data, levelKey)
-	}
+func prefixFieldClashes(data []Field, fieldMap FieldMap, reportCaller bool, named bool) []Field {
+	data = prefixFieldClash(data, fieldMap.resolve(FieldKeyTime))
+	data = prefixFieldClash(data, fieldMap.resolve(FieldKeyMsg))
+	data = prefixFieldClash(data, fieldMap.resolve(FieldKeyLevel))
+	data = prefixFieldClash(data, fieldMap.resolve(FieldKeyHmiLogError))
 
-	hlogErrKey := fieldMap.resolve(FieldKeyHmiLogError)
-	if l, ok := data[hlogErrKey]; ok {
-		data["fields."+hlogErrKey] = l
-		delete(data, hlogErrKey)
+	// Only named loggers emit the 'logger' field.
+	if named {
+		data = prefixFieldClash(data, fieldMap.resolve(FieldKeyLogger))
 	}
 
 	// If reportCaller is not set, 'func' will not conflict.
 	if reportCaller {
-		funcKey := fieldMap.resolve(FieldKeyFunc)
-		if l, ok := data[funcKey]; ok {
-			data["fields."+funcKey] = l
-		}
-		fileKey := fieldMap.resolve(FieldKeyFile)
-		if l, ok := data[fileKey]; ok {
-			data["fields."+fileKey] = l
+		for _, key := range [...]string{fieldMap.resolve(FieldKeyFunc), fieldMap.resolve(FieldKeyFile)} {
+			if i := fieldIndex(data, key); i >= 0 {
+				f := data[i]
+				f.key = "fields." + key
+				data = setField(data, f)
+			}
 		}
 	}
-
+	return data
 }
 
-func prefixFieldClashesFixed(data Fields) {
-	if t, ok := data["time"]; ok {
-		data["fields.time"] = t
+// prefixFieldClash renames the field with the given key to "fields.<key>",
+// replacing any field already named so.
+func prefixFieldClash(data []Field, key string) []Field {
+	i := fieldIndex(data, key)
+	if i < 0 {
+		return data
 	}
+	f := data[i]
+	data = append(data[:i], data[i+1:]...)
+	f.key = "fields." + key
+	return setField(data, f)
+}
 
-	if m, ok := data["msg"]; ok {
-		data["fields.msg"] = m
+// setField replaces the field with the same key as f, or appends f.
+func setField(data []Field, f Field) []Field {
+	if i := fieldIndex(data, f.key); i >= 0 {
+		data[i] = f
+		return data
 	}
+	return append(data, f)
+}
 
-	if l, ok := data["level"]; ok {
-		data["fields.level"] = l
+// prefixTypedFieldClashes is the typed field counterpart of
+// prefixFieldClashes, the fields are renamed in place.
+func prefixTypedFieldClashes(fields []Field, fieldMap FieldMap, reportCaller bool, named bool) {
+	for i := range fields {
+		switch fields[i].key {
+		case fieldMap.resolve(FieldKeyTime), fieldMap.resolve(FieldKeyMsg),
+			fieldMap.resolve(FieldKeyLevel), fieldMap.resolve(FieldKeyHmiLogError):
+		case fieldMap.resolve(FieldKeyFunc), fieldMap.resolve(FieldKeyFile):
+			if !reportCaller {
+				continue
+			}
+		case fieldMap.resolve(FieldKeyLogger):
+			if !named {
+				continue
+			}
+		default:
+			continue
+		}
+		fields[i].key = "fields." + fields[i].key
 	}
 }
@@ -0,0 +1,172 @@
+package hlog
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type formatterCase struct {
+	name      string
+	formatter Formatter
+	entry     func(logger *Logger) *Entry
+	want      string
+}
+
+func formatterEntry(entry *Entry, msg string) *Entry {
+	entry.Time = time.Date(2024, 1, 2, 3, 4, 5, 600, time.FixedZone("CET", 3600))
+	entry.Level = WarnLevel
+	entry.Message = msg
+	return entry
+}
+
+var formatterCaller = &runtime.Frame{Function: "example.com/app.handle", File: "/src/app/handle.go", Line: 42}
+
+func formatterCases() []formatterCase {
+	return []formatterCase{
+		{
+			name:      "JSON/values",
+			formatter: &JSONFormatter{},
+			entry: func(logger *Logger) *Entry {
+				return formatterEntry(logger.WithFields(Fields{
+					"str":   "a <b> & \"c\"\n",
+					"int":   42,
+					"uint8": uint8(7),
+					"float": 1.5,
+					"big":   1e21,
+					"bool":  true,
+					"nil":   nil,
+					"err":   errors.New("boom"),
+					"dur":   time.Second,
+					"map":   map[string]int{"b": 2, "a": 1},
+					"slice": []interface{}{"x", 1},
+					"level": InfoLevel,
+				}).With(Str("typed", "t"), Int("n", -3), Dur("took", time.Millisecond), Err(errors.New("typed"))), "hello <world>")
+			},
+			want: `{"big":1e+21,"bool":true,"dur":1000000000,"err":"boom","fields.level":"info","float":1.5,"int":42,"level":"warning","map":{"a":1,"b":2},"msg":"hello \u003cworld\u003e","nil":null,"slice":["x",1],"str":"a \u003cb\u003e \u0026 \"c\"\n","time":"2024-01-02T03:04:05+01:00","uint8":7,"typed":"t","n":-3,"took":"1ms","error":"typed"}` + "\n",
+		},
+		{
+			name:      "JSON/clashes",
+			formatter: &JSONFormatter{},
+			entry: func(logger *Logger) *Entry {
+				logger.SetReportCaller(true)
+				entry := formatterEntry(logger.Named("db").WithFields(Fields{
+					"time":        "t",
+					"msg":         "m",
+					"hlog_error":  "e",
+					"fields.msg":  "shadowed",
+					"logger":      "l",
+					"func":        "f",
+					"file":        "fi",
+					"level":       "boxed",
+					"fields.time": "old",
+				}).WithField("bad", func() {}).With(Str("level", "typed"), Str("file", "typed")), "clash")
+				entry.Caller = formatterCaller
+				return entry
+			},
+			want: `{"fields.func":"f","fields.hlog_error":"e","fields.logger":"l","fields.msg":"m","fields.time":"t","file":"/src/app/handle.go:42","func":"example.com/app.handle","hlog_error":"can not add field \"bad\"","level":"warning","logger":"db","msg":"clash","time":"2024-01-02T03:04:05+01:00","fields.level":"typed","fields.file":"typed"}` + "\n",
+		},
+		{
+			name: "JSON/datakey",
+			formatter: &JSONFormatter{
+				DataKey:          "data",
+				DisableTimestamp: true,
+				FieldMap:         FieldMap{FieldKeyMsg: "message", FieldKeyTraceID: "trace.id"},
+			},
+			entry: func(logger *Logger) *Entry {
+				return formatterEntry(logger.WithFields(Fields{
+					"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",
+					"b":        2,
+					"a":        1,
+					"message":  "boxed",
+				}).With(Str(FieldKeySpanID, "00f067aa0ba902b7"), Str("typed", "t")), "nested")
+			},
+			want: `{"data":{"a":1,"b":2,"message":"boxed","typed":"t"},"level":"warning","message":"nested","trace.id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7"}` + "\n",
+		},
+		{
+			name: "JSON/options",
+			formatter: &JSONFormatter{
+				TimestampFormat:   time.RFC3339Nano,
+				DisableHTMLEscape: true,
+				CallerPrettier: func(f *runtime.Frame) (string, string) {
+					return "handle", ""
+				},
+				FieldMap: FieldMap{FieldKeyTime: "@timestamp", FieldKeyLevel: "@level", FieldKeyFunc: "@caller"},
+			},
+			entry: func(logger *Logger) *Entry {
+				logger.SetReportCaller(true)
+				entry := formatterEntry(logger.WithFields(Fields{"html": "<b>", "@level": 1, "trace_id": errors.New("x")}), "<options>")
+				entry.Caller = formatterCaller
+				return entry
+			},
+			want: `{"@caller":"handle","@level":"warning","@timestamp":"2024-01-02T03:04:05.0000006+01:00","fields.@level":1,"html":"<b>","msg":"<options>","trace_id":"x"}` + "\n",
+		},
+		{
+			name:      "JSON/pretty",
+			formatter: &JSONFormatter{PrettyPrint: true},
+			entry: func(logger *Logger) *Entry {
+				return formatterEntry(logger.With(Int("a", 1)).WithField("b", []int{1, 2}), "pretty")
+			},
+			want: "{\n  \"b\": [\n    1,\n    2\n  ],\n  \"level\": \"warning\",\n  \"msg\": \"pretty\",\n  \"time\": \"2024-01-02T03:04:05+01:00\",\n  \"a\": 1\n}\n",
+		},
+		{
+			name:      "Text/values",
+			formatter: &TextFormatter{DisableColors: true},
+			entry: func(logger *Logger) *Entry {
+				return formatterEntry(logger.WithFields(Fields{
+					"str":   "plain",
+					"quote": "needs quoting",
+					"empty": "",
+					"int":   42,
+					"float": 1.5,
+					"bool":  false,
+					"nil":   nil,
+					"err":   errors.New("some error"),
+					"err2":  errors.New("simple"),
+					"dur":   time.Second,
+					"slice": []string{"a", "b"},
+				}).With(Str("typed", "a b"), Int("n", 3), Float64("f", 0.25), Err(errors.New("typed error"))), "hello world")
+			},
+			want: `time="2024-01-02T03:04:05+01:00" level=warning msg="hello world" bool=false dur=1s empty= err="some error" err2=simple error="typed error" f=0.25 float=1.5 int=42 n=3 nil=<nil> quote="needs quoting" slice=[a b] str=plain typed="a b"` + "\n",
+		},
+		{
+			name:      "Text/clashes",
+			formatter: &TextFormatter{DisableColors: true, QuoteEmptyFields: true, DisableTimestamp: true},
+			entry: func(logger *Logger) *Entry {
+				return formatterEntry(logger.Named("db").WithFields(Fields{"time": "t", "msg": "", "level": 1}), "")
+			},
+			want: `level=warning logger=db level=1 msg="" time=t` + "\n",
+		},
+		{
+			name:      "Text/stack",
+			formatter: &TextFormatter{DisableColors: true, TimestampFormat: time.Kitchen},
+			entry: func(logger *Logger) *Entry {
+				st := StackTrace{{Function: "main.main", File: "/src/main.go", Line: 3}}
+				return formatterEntry(logger.WithField("b", 1).WithField(FieldKeyStack, st).With(Any("a", st)), "stack")
+			},
+			want: "time=\"3:04AM\" level=warning msg=stack b=1\na:\n\tmain.main\n\t\t/src/main.go:3\nstack:\n\tmain.main\n\t\t/src/main.go:3\n",
+		},
+	}
+}
+
+func TestFormatters(t *testing.T) {
+	for _, c := range formatterCases() {
+		t.Run(c.name, func(t *testing.T) {
+			logger := New()
+			logger.Formatter = c.formatter
+			entry := c.entry(logger)
+			b, err := c.formatter.Format(entry)
+			require.NoError(t, err)
+			assert.Equal(t, c.want, string(b))
+
+			// Formatting again gives the same output.
+			b, err = c.formatter.Format(entry)
+			require.NoError(t, err)
+			assert.Equal(t, c.want, string(b))
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"runtime"
+	"sort"
+	"sync"
 )
 
 type fieldKey string
@@ -45,6 +47,8 @@ type JSONFormatter struct {
 	// 		 FieldKeyLevel: "@level",
 	// 		 FieldKeyMsg:   "@message",
 	// 		 FieldKeyFunc:  "@caller",
+	// 		 FieldKeyLogger: "@logger",
+	// 		 FieldKeyTraceID: "trace.id",
 	//    },
 	// }
 	FieldMap FieldMap
@@ -59,27 +63,84 @@ type JSONFormatter struct {
 	PrettyPrint bool
 }
 
+// Kinds of the builtin fields of the JSONFormatter written straight to the
+// buffer, instead of being formatted to strings first.
+const (
+	// timestampField holds a *time.Time, and the layout in stringValue
+	timestampField FieldKind = -1 - iota
+	// callerFileField holds a *runtime.Frame
+	callerFileField
+)
+
+// jsonState holds the scratch slices of Format, recycled with jsonStatePool
+// so that formatting doesn't allocate.
+type jsonState struct {
+	data   []Field
+	fields []Field
+	trace  []Field
+	outer  []Field
+	nested jsonObject
+}
+
+var jsonStatePool = sync.Pool{
+	New: func() interface{} {
+		return new(jsonState)
+	},
+}
+
+func (s *jsonState) release() {
+	s.data = clearFields(s.data)
+	s.fields = clearFields(s.fields)
+	s.trace = clearFields(s.trace)
+	s.outer = clearFields(s.outer)
+	s.nested = jsonObject{}
+	jsonStatePool.Put(s)
+}
+
+// clearFields empties fields, without keeping their values alive.
+func clearFields(fields []Field) []Field {
+	for i := range fields {
+		fields[i] = Field{}
+	}
+	return fields[:0]
+}
+
 // Format renders a single log entry
 func (f *JSONFormatter) Format(entry *Entry) ([]byte, error) {
-	data := make(Fields, len(entry.Data)+4)
+	s := jsonStatePool.Get().(*jsonState)
+	defer s.release()
+
 	for k, v := range entry.Data {
-		switch v := v.(type) {
-		case error:
+		if fieldIndex(entry.fields, k) >= 0 {
+			// shadowed by a typed field
+			continue
+		}
+		if err, ok := v.(error); ok {
 			// Otherwise errors are ignored by `encoding/json`
 			// https://github.com/sirupsen/hlog/issues/137
-			data[k] = v.Error()
-		default:
-			data[k] = v
+			s.data = append(s.data, Str(k, err.Error()))
+		} else {
+			s.data = append(s.data, Any(k, v))
 		}
 	}
+	s.fields = append(s.fields, entry.fields...)
+	// The trace correlation fields stay next to the builtin ones.
+	s.data, s.fields, s.trace = takeTraceFields(s.data, s.fields, s.trace, f.FieldMap)
 
+	name := entry.LoggerName()
+	data := &s.data
+	var fields []Field
 	if f.DataKey != "" {
-		newData := make(Fields, 4)
-		newData[f.DataKey] = data
-		data = newData
+		s.nested = jsonObject{data: s.data, fields: s.fields}
+		s.outer = append(s.outer, Any(f.DataKey, &s.nested))
+		data = &s.outer
+		fields = s.trace
+	} else {
+		prefixTypedFieldClashes(s.fields, f.FieldMap, entry.HasCaller(), name != "")
+		s.fields = append(s.fields, s.trace...)
+		fields = s.fields
 	}
-
-	prefixFieldClashes(data, f.FieldMap, entry.HasCaller())
+	*data = prefixFieldClashes(*data, f.FieldMap, entry.HasCaller(), name != "")
 
 	timestampFormat := f.TimestampFormat
 	if timestampFormat == "" {
@@ -87,24 +148,30 @@ func (f *JSONFormatter) Format(entry *Entry) ([]byte, error) {
 	}
 
 	if entry.err != "" {
-		data[f.FieldMap.resolve(FieldKeyHmiLogError)] = entry.err
+		*data = setField(*data, Str(f.FieldMap.resolve(FieldKeyHmiLogError), entry.err))
 	}
 	if !f.DisableTimestamp {
-		data[f.FieldMap.resolve(FieldKeyTime)] = entry.Time.Format(timestampFormat)
+		*data = setField(*data, Field{kind: timestampField, key: f.FieldMap.resolve(FieldKeyTime), stringValue: timestampFormat, interfaceValue: &entry.Time})
+	}
+	*data = setField(*data, Str(f.FieldMap.resolve(FieldKeyMsg), entry.Message))
+	*data = setField(*data, Str(f.FieldMap.resolve(FieldKeyLevel), entry.Level.String()))
+	if name != "" {
+		*data = setField(*data, Str(f.FieldMap.resolve(FieldKeyLogger), name))
 	}
-	data[f.FieldMap.resolve(FieldKeyMsg)] = entry.Message
-	data[f.FieldMap.resolve(FieldKeyLevel)] = entry.Level.String()
 	if entry.HasCaller() {
-		funcVal := entry.Caller.Function
-		fileVal := fmt.Sprintf("%s:%d", entry.Caller.File, entry.Caller.Line)
 		if f.CallerPrettier != nil {
-			funcVal, fileVal = f.CallerPrettier(entry.Caller)
-		}
-		if funcVal != "" {
-			data[f.FieldMap.resolve(FieldKeyFunc)] = funcVal
-		}
-		if fileVal != "" {
-			data[f.FieldMap.resolve(FieldKeyFile)] = fileVal
+			funcVal, fileVal := f.CallerPrettier(entry.Caller)
+			if funcVal != "" {
+				*data = setField(*data, Str(f.FieldMap.resolve(FieldKeyFunc), funcVal))
+			}
+			if fileVal != "" {
+				*data = setField(*data, Str(f.FieldMap.resolve(FieldKeyFile), fileVal))
+			}
+		} else {
+			if entry.Caller.Function != "" {
+				*data = setField(*data, Str(f.FieldMap.resolve(FieldKeyFunc), entry.Caller.Function))
+			}
+			*data = setField(*data, Field{kind: callerFileField, key: f.FieldMap.resolve(FieldKeyFile), interfaceValue: entry.Caller})
 		}
 	}
 
@@ -115,14 +182,47 @@ func (f *JSONFormatter) Format(entry *Entry) ([]byte, error) {
 		b = &bytes.Buffer{}
 	}
 
-	encoder := json.NewEncoder(b)
-	encoder.SetEscapeHTML(!f.DisableHTMLEscape)
-	if f.PrettyPrint {
-		encoder.SetIndent("", "  ")
-	}
-	if err := encoder.Encode(data); err != nil {
+	e := jsonEncoder{b: b, escapeHTML: !f.DisableHTMLEscape}
+	start := b.Len()
+	if err := e.writeObject(*data, fields); err != nil {
 		return nil, fmt.Errorf("failed to marshal fields to JSON, %w", err)
 	}
+	if f.PrettyPrint {
+		compact := append([]byte(nil), b.Bytes()[start:]...)
+		b.Truncate(start)
+		if err := json.Indent(b, compact, "", "  "); err != nil {
+			return nil, fmt.Errorf("failed to marshal fields to JSON, %w", err)
+		}
+	}
+	b.WriteByte('\n')
 
 	return b.Bytes(), nil
 }
+
+// jsonObject is a nested object made of boxed and typed fields, used for
+// the DataKey option.
+type jsonObject struct {
+	data   []Field
+	fields []Field
+}
+
+// fieldsByKey sorts fields by key.
+type fieldsByKey []Field
+
+func (s fieldsByKey) Len() int           { return len(s) }
+func (s fieldsByKey) Less(i, j int) bool { return s[i].key < s[j].key }
+func (s fieldsByKey) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// sortFields sorts fields by key. The usual handful of fields is insertion
+// sorted, sort.Sort would allocate.
+func sortFields(fields []Field) {
+	if len(fields) > 16 {
+		sort.Sort(fieldsByKey(fields))
+		return
+	}
+	for i := 1; i < len(fields); i++ {
+		for j := i; j > 0 && fields[j].key < fields[j-1].key; j-- {
+			fields[j], fields[j-1] = fields[j-1], fields[j]
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -213,13 +214,34 @@ func (f *TextFormatter) SetColorScheme(colorScheme *ColorScheme) {
 	f.colorScheme = compileColorScheme(colorScheme)
 }
 
+// textKeysPool recycles the key slices of Format.
+var textKeysPool = sync.Pool{
+	New: func() interface{} {
+		keys := make([]string, 0, 16)
+		return &keys
+	},
+}
+
 // Format renders a single log entry
 func (f *TextFormatter) Format(entry *Entry) ([]byte, error) {
 	var b *bytes.Buffer
-	var keys []string = make([]string, 0, len(entry.Data))
+	pooledKeys := textKeysPool.Get().(*[]string)
+	defer func() {
+		*pooledKeys = (*pooledKeys)[:0]
+		textKeysPool.Put(pooledKeys)
+	}()
+	keys := (*pooledKeys)[:0]
 	for k := range entry.Data {
-		keys = append(keys, k)
+		if fieldIndex(entry.fields, k) < 0 {
+			keys = append(keys, k)
+		}
+	}
+	for i := range entry.fields {
+		keys = append(keys, entry.fields[i].key)
 	}
+	*pooledKeys = keys
+	// Stack traces don't fit on the line, they are written below it.
+	keys, stacks := splitStacks(entry, keys)
 	lastKeyIdx := len(keys) - 1
 
 	if !f.DisableSorting {
@@ -231,8 +253,6 @@ func (f *TextFormatter) Format(entry *Entry) ([]byte, error) {
 		b = &bytes.Buffer{}
 	}
 
-	prefixFieldClashesFixed(entry.Data)
-
 	f.Do(func() { f.init(entry) })
 
 	isFormatted := f.ForceFormatting || f.isTerminal
@@ -256,21 +276,67 @@ func (f *TextFormatter) Format(entry *Entry) ([]byte, error) {
 		f.printColored(b, entry, keys, timestampFormat, colorScheme)
 	} else {
 		if !f.DisableTimestamp {
-			f.appendKeyValue(b, "time", entry.Time.Format(timestampFormat), true)
+			var scratch [64]byte
+			b.WriteString("time=")
+			f.appendBytes(b, entry.Time.AppendFormat(scratch[:0], timestampFormat))
+			b.WriteByte(' ')
+		}
+		f.appendKeyString(b, "level", entry.Level.String(), true)
+		if name := entry.LoggerName(); name != "" {
+			f.appendKeyString(b, FieldKeyLogger, name, true)
 		}
-		f.appendKeyValue(b, "level", entry.Level.String(), true)
 		if entry.Message != "" {
-			f.appendKeyValue(b, "msg", entry.Message, lastKeyIdx >= 0)
+			f.appendKeyString(b, "msg", entry.Message, lastKeyIdx >= 0)
 		}
 		for i, key := range keys {
-			f.appendKeyValue(b, key, entry.Data[key], lastKeyIdx != i)
+			if j := fieldIndex(entry.fields, key); j >= 0 {
+				f.appendKeyField(b, entry.fields[j], lastKeyIdx != i)
+			} else {
+				f.appendKeyValue(b, key, entry.Data[key], lastKeyIdx != i)
+			}
 		}
 	}
 
 	b.WriteByte('\n')
+	for _, stack := range stacks {
+		b.WriteString(stack.key)
+		b.WriteString(":\n")
+		stack.trace.appendText(b, "\t")
+	}
 	return b.Bytes(), nil
 }
 
+type textStack struct {
+	key   string
+	trace StackTrace
+}
+
+// splitStacks removes the keys of the stack trace fields from keys and
+// returns them with their value.
+func splitStacks(entry *Entry, keys []string) ([]string, []textStack) {
+	var stacks []textStack
+	n := 0
+	for _, k := range keys {
+		var v interface{}
+		if j := fieldIndex(entry.fields, k); j >= 0 {
+			// Only boxed values can be stack traces.
+			v = entry.fields[j].interfaceValue
+		} else {
+			v = entry.Data[k]
+		}
+		if st, ok := v.(StackTrace); ok {
+			stacks = append(stacks, textStack{key: k, trace: st})
+			continue
+		}
+		keys[n] = k
+		n++
+	}
+	if len(stacks) > 1 {
+		sort.Slice(stacks, func(i, j int) bool { return stacks[i].key < stacks[j].key })
+	}
+	return keys[:n], stacks
+}
+
 func (f *TextFormatter) printColored(b *bytes.Buffer, entry *Entry, keys []string, timestampFormat string, colorScheme *compiledColorScheme) {
 	var levelColor func(string) string
 	var levelText string
@@ -305,6 +371,8 @@ func (f *TextFormatter) printColored(b *bytes.Buffer, entry *Entry, keys []strin
 
 	if prefixValue, ok := entry.Data["prefix"]; ok {
 		prefix = colorScheme.PrefixColor(" " + prefixValue.(string) + ":")
+	} else if name := entry.LoggerName(); name != "" {
+		prefix = colorScheme.PrefixColor(" " + name + ":")
 	} else {
 		prefixValue, trimmedMsg := extractPrefix(entry.Message)
 		if len(prefixValue) > 0 {
@@ -330,7 +398,13 @@ func (f *TextFormatter) printColored(b *bytes.Buffer, entry *Entry, keys []strin
 		fmt.Fprintf(b, "%s %s%s "+messageFormat, colorScheme.TimestampColor(timestamp), level, prefix, message)
 	}
 	for _, k := range keys {
-		if k != "prefix" {
+		if k == "prefix" {
+			continue
+		}
+		if j := fieldIndex(entry.fields, k); j >= 0 {
+			fmt.Fprintf(b, " %s=", levelColor(k))
+			entry.fields[j].appendText(b)
+		} else {
 			v := entry.Data[k]
 			fmt.Fprintf(b, " %s=%+v", levelColor(k), v)
 		}
@@ -351,17 +425,41 @@ func (f *TextFormatter) needsQuoting(text string) bool {
 	if f.QuoteEmptyFields && len(text) == 0 {
 		return true
 	}
-	for _, ch := range text {
-		if !((ch >= 'a' && ch <= 'z') ||
-			(ch >= 'A' && ch <= 'Z') ||
-			(ch >= '0' && ch <= '9') ||
-			ch == '-' || ch == '.') {
+	for i := 0; i < len(text); i++ {
+		if !isTextSafe(text[i]) {
 			return true
 		}
 	}
 	return false
 }
 
+// isTextSafe reports whether c can be written unquoted. Multi-byte runes
+// need quoting, so checking the bytes is enough.
+func isTextSafe(c byte) bool {
+	return (c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9') ||
+		c == '-' || c == '.'
+}
+
+// appendBytes is appendString for text formatted to a scratch buffer.
+func (f *TextFormatter) appendBytes(b *bytes.Buffer, value []byte) {
+	quote := f.QuoteEmptyFields && len(value) == 0
+	for _, c := range value {
+		if !isTextSafe(c) {
+			quote = true
+			break
+		}
+	}
+	if quote {
+		b.WriteString(f.QuoteCharacter)
+	}
+	b.Write(value)
+	if quote {
+		b.WriteString(f.QuoteCharacter)
+	}
+}
+
 func (f *TextFormatter) appendKeyValue(b *bytes.Buffer, key string, value interface{}, appendSpace bool) {
 	b.WriteString(key)
 	b.WriteByte('=')
@@ -372,21 +470,57 @@ func (f *TextFormatter) appendKeyValue(b *bytes.Buffer, key string, value interf
 	}
 }
 
+func (f *TextFormatter) appendKeyString(b *bytes.Buffer, key string, value string, appendSpace bool) {
+	b.WriteString(key)
+	b.WriteByte('=')
+	f.appendString(b, value)
+
+	if appendSpace {
+		b.WriteByte(' ')
+	}
+}
+
+func (f *TextFormatter) appendKeyField(b *bytes.Buffer, field Field, appendSpace bool) {
+	b.WriteString(field.key)
+	b.WriteByte('=')
+	if text, ok := field.text(); ok {
+		f.appendString(b, text)
+	} else {
+		field.appendText(b)
+	}
+
+	if appendSpace {
+		b.WriteByte(' ')
+	}
+}
+
+func (f *TextFormatter) appendString(b *bytes.Buffer, value string) {
+	if !f.needsQuoting(value) {
+		b.WriteString(value)
+	} else {
+		b.WriteString(f.QuoteCharacter)
+		b.WriteString(value)
+		b.WriteString(f.QuoteCharacter)
+	}
+}
+
 func (f *TextFormatter) appendValue(b *bytes.Buffer, value interface{}) {
+	var scratch [64]byte
 	switch value := value.(type) {
 	case string:
-		if !f.needsQuoting(value) {
-			b.WriteString(value)
-		} else {
-			fmt.Fprintf(b, "%s%v%s", f.QuoteCharacter, value, f.QuoteCharacter)
-		}
+		f.appendString(b, value)
 	case error:
-		errmsg := value.Error()
-		if !f.needsQuoting(errmsg) {
-			b.WriteString(errmsg)
-		} else {
-			fmt.Fprintf(b, "%s%v%s", f.QuoteCharacter, errmsg, f.QuoteCharacter)
-		}
+		f.appendString(b, value.Error())
+	case int:
+		b.Write(strconv.AppendInt(scratch[:0], int64(value), 10))
+	case int64:
+		b.Write(strconv.AppendInt(scratch[:0], value, 10))
+	case uint64:
+		b.Write(strconv.AppendUint(scratch[:0], value, 10))
+	case float64:
+		b.Write(strconv.AppendFloat(scratch[:0], value, 'g', -1, 64))
+	case bool:
+		b.Write(strconv.AppendBool(scratch[:0], value))
 	default:
 		fmt.Fprint(b, value)
 	}
@@ -23,9 +23,11 @@ func runHandlers() {
 	}
 }
 
-// Exit runs all the hlog atexit handlers and then terminates the program using os.Exit(code)
+// Exit runs all the hlog atexit handlers, shuts down the standard logger and
+// then terminates the program using os.Exit(code)
 func Exit(code int) {
 	runHandlers()
+	std.shutdownForExit()
 	os.Exit(code)
 }
 
@@ -14,11 +14,23 @@ type Level uint32
 
 // Convert the Level to a string. E.g. PanicLevel becomes "panic".
 func (level Level) String() string {
-	if b, err := level.MarshalText(); err == nil {
-		return string(b)
-	} else {
-		return "unknown"
+	switch level {
+	case TraceLevel:
+		return "trace"
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warning"
+	case ErrorLevel:
+		return "error"
+	case FatalLevel:
+		return "fatal"
+	case PanicLevel:
+		return "panic"
 	}
+	return "unknown"
 }
 
 // ParseLevel takes a string level and returns the hlog log level constant.
@@ -57,24 +69,10 @@ func (level *Level) UnmarshalText(text []byte) error {
 }
 
 func (level Level) MarshalText() ([]byte, error) {
-	switch level {
-	case TraceLevel:
-		return []byte("trace"), nil
-	case DebugLevel:
-		return []byte("debug"), nil
-	case InfoLevel:
-		return []byte("info"), nil
-	case WarnLevel:
-		return []byte("warning"), nil
-	case ErrorLevel:
-		return []byte("error"), nil
-	case FatalLevel:
-		return []byte("fatal"), nil
-	case PanicLevel:
-		return []byte("panic"), nil
+	if level > TraceLevel {
+		return nil, fmt.Errorf("not a valid hlog level %d", level)
 	}
-
-	return nil, fmt.Errorf("not a valid hlog level %d", level)
+	return []byte(level.String()), nil
 }
 
 // A constant exposing all logging levels
@@ -0,0 +1,176 @@
+package hlog
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HookError is the error returned by a hook when firing an entry. Hook is
+// nil for the errors of the output of a logger, see Logger.Shutdown.
+type HookError struct {
+	Hook Hook
+	Err  error
+}
+
+func (e *HookError) Error() string {
+	if e.Hook == nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%T: %v", e.Hook, e.Err)
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// HookErrors aggregates the errors of the hooks fired for an entry, or of
+// the hooks and the output shut down by Logger.Shutdown.
+type HookErrors []*HookError
+
+func (errs HookErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors, for errors.Is and errors.As.
+func (errs HookErrors) Unwrap() []error {
+	unwrapped := make([]error, len(errs))
+	for i, err := range errs {
+		unwrapped[i] = err
+	}
+	return unwrapped
+}
+
+// FireAll fires all the hooks of the level, even after one of them failed,
+// and returns their errors as HookErrors.
+func (hooks LevelHooks) FireAll(level Level, entry *Entry) error {
+	var errs HookErrors
+	for _, hook := range hooks[level] {
+		if err := hook.Fire(entry); err != nil {
+			errs = append(errs, &HookError{Hook: hook, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// HookErrorHandler is called with the entry for each error returned by a
+// hook.
+type HookErrorHandler func(hook Hook, entry *Entry, err error)
+
+// HookErrorPolicy tells a Logger what to do when hooks fail. The zero value
+// fires all the hooks whatever their errors.
+type HookErrorPolicy struct {
+	// StopOnError skips the hooks left after a failing one, like
+	// LevelHooks.Fire does.
+	StopOnError bool
+	// DisableAfter is the number of consecutive failures after which a
+	// hook is not fired anymore for the Cooldown period. Zero never
+	// disables hooks.
+	DisableAfter int
+	Cooldown     time.Duration
+}
+
+// SetHookErrorPolicy sets the policy applied when hooks fail.
+func (logger *Logger) SetHookErrorPolicy(policy HookErrorPolicy) {
+	logger.lock().Lock()
+	logger.hookPolicy = policy
+	logger.lock().Unlock()
+	logger.eachChild(func(child *Logger) {
+		child.SetHookErrorPolicy(policy)
+	})
+}
+
+// SetErrorHandler sets the handler called with the errors of the hooks,
+// which are printed on stderr by default.
+func (logger *Logger) SetErrorHandler(handler HookErrorHandler) {
+	logger.lock().Lock()
+	logger.ErrorHandler = handler
+	logger.lock().Unlock()
+	logger.eachChild(func(child *Logger) {
+		child.SetErrorHandler(handler)
+	})
+}
+
+// hookBreaker tracks the consecutive failures of hooks to disable them
+// according to a HookErrorPolicy. It is shared by the loggers of a tree.
+type hookBreaker struct {
+	mu    sync.Mutex
+	state map[Hook]*hookState
+}
+
+type hookState struct {
+	failures      int
+	disabledUntil time.Time
+}
+
+// breakable reports whether the hook can be tracked, hooks of types that
+// can't be map keys are never disabled.
+func breakable(hook Hook) bool {
+	return reflect.TypeOf(hook).Comparable()
+}
+
+func (b *hookBreaker) disabled(hook Hook, now time.Time) bool {
+	if !breakable(hook) {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.state[hook]
+	if !ok || state.disabledUntil.IsZero() {
+		return false
+	}
+	if now.Before(state.disabledUntil) {
+		return true
+	}
+	delete(b.state, hook)
+	return false
+}
+
+func (b *hookBreaker) failure(hook Hook, policy HookErrorPolicy, now time.Time) {
+	if policy.DisableAfter <= 0 || !breakable(hook) {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == nil {
+		b.state = make(map[Hook]*hookState)
+	}
+	state, ok := b.state[hook]
+	if !ok {
+		state = &hookState{}
+		b.state[hook] = state
+	}
+	if state.failures++; state.failures >= policy.DisableAfter {
+		state.disabledUntil = now.Add(policy.Cooldown)
+	}
+}
+
+func (b *hookBreaker) success(hook Hook) {
+	if !breakable(hook) {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.state, hook)
+}
+
+// handleHookErrors passes the errors of the hooks to the error handler.
+func (entry *Entry) handleHookErrors(handler HookErrorHandler, errs HookErrors) {
+	for _, err := range errs {
+		if handler != nil {
+			handler(err.Hook, entry, err.Err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Failed to fire hook %T: %v\n", err.Hook, err.Err)
+		}
+	}
+}
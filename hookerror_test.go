@@ -0,0 +1,99 @@
+package hlog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingHook counts the entries it is fired with and fails.
+type failingHook struct {
+	fired int
+	err   error
+}
+
+func (h *failingHook) Levels() []Level {
+	return AllLevels
+}
+
+func (h *failingHook) Fire(*Entry) error {
+	h.fired++
+	return h.err
+}
+
+type hookFailure struct {
+	hook  Hook
+	entry *Entry
+	err   error
+}
+
+func newHookErrorLogger() (*Logger, *[]hookFailure) {
+	var failures []hookFailure
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.SetErrorHandler(func(hook Hook, entry *Entry, err error) {
+		failures = append(failures, hookFailure{hook, entry, err})
+	})
+	return logger, &failures
+}
+
+func TestHookErrorsContinue(t *testing.T) {
+	logger, failures := newHookErrorLogger()
+	graylog := &failingHook{err: errors.New("graylog down")}
+	sentry := &failingHook{err: errors.New("sentry down")}
+	last := &failingHook{}
+	logger.AddHook(graylog)
+	logger.AddHook(sentry)
+	logger.AddHook(last)
+
+	logger.Error("boom")
+
+	assert.Equal(t, 1, last.fired)
+	require.Len(t, *failures, 2)
+	assert.Same(t, graylog, (*failures)[0].hook)
+	assert.Equal(t, "boom", (*failures)[0].entry.Message)
+	assert.EqualError(t, (*failures)[1].err, "sentry down")
+
+	err := logger.Hooks.FireAll(ErrorLevel, NewEntry(logger))
+	var errs HookErrors
+	require.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "*hlog.failingHook: graylog down; *hlog.failingHook: sentry down", err.Error())
+}
+
+func TestHookErrorsStop(t *testing.T) {
+	logger, failures := newHookErrorLogger()
+	logger.SetHookErrorPolicy(HookErrorPolicy{StopOnError: true})
+	failing := &failingHook{err: errors.New("down")}
+	last := &failingHook{}
+	logger.AddHook(failing)
+	logger.AddHook(last)
+
+	logger.Info("boom")
+
+	assert.Equal(t, 0, last.fired)
+	assert.Len(t, *failures, 1)
+}
+
+func TestHookErrorsCooldown(t *testing.T) {
+	logger, failures := newHookErrorLogger()
+	logger.SetHookErrorPolicy(HookErrorPolicy{DisableAfter: 2, Cooldown: 20 * time.Millisecond})
+	failing := &failingHook{err: errors.New("down")}
+	logger.AddHook(failing)
+	child := logger.Named("child")
+
+	logger.Info("one")
+	child.Info("two")
+	logger.Info("disabled")
+	child.Info("disabled")
+	assert.Equal(t, 2, failing.fired)
+	assert.Len(t, *failures, 2)
+
+	time.Sleep(30 * time.Millisecond)
+	logger.Info("enabled again")
+	assert.Equal(t, 3, failing.fired)
+}
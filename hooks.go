@@ -21,3 +21,11 @@ func (hooks LevelHooks) Fire(level Level, entry *Entry) error {
 	}
 	return nil
 }
+
+func (hooks LevelHooks) copy() LevelHooks {
+	dup := make(LevelHooks, len(hooks))
+	for level, levelHooks := range hooks {
+		dup[level] = append([]Hook(nil), levelHooks...)
+	}
+	return dup
+}
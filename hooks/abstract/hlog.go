@@ -4,6 +4,11 @@ import (
 	"github.com/adminhmi/hlog"
 )
 
+func init() {
+	// Report the code calling the adapter as the caller, not the adapter.
+	hlog.AddCallerSkipPackages("github.com/adminhmi/hlog/hooks/abstract")
+}
+
 type Logger interface {
 	DebugLogger
 	InfoLogger
@@ -62,24 +67,24 @@ func (l *hlogLogger) LevelLogger(level Level) LevelLogger {
 	}
 }
 
-func (l *hlogLogger) fields(fields []Field) hlog.Fields {
-	out := make(hlog.Fields, len(fields))
+func (l *hlogLogger) fields(fields []Field) []hlog.Field {
+	out := make([]hlog.Field, len(fields))
 	for i := range fields {
 		switch fields[i].kind {
 		case StringField:
-			out[fields[i].key] = fields[i].stringValue
+			out[i] = hlog.Str(fields[i].key, fields[i].stringValue)
 		case ByteStringField:
-			out[fields[i].key] = string(fields[i].byteValue)
+			out[i] = hlog.ByteString(fields[i].key, fields[i].byteValue)
 		case IntField:
-			out[fields[i].key] = fields[i].intValue
+			out[i] = hlog.Int64(fields[i].key, fields[i].intValue)
 		case BoolField:
-			out[fields[i].key] = fields[i].intValue != 0
+			out[i] = hlog.Bool(fields[i].key, fields[i].intValue != 0)
 		case ErrorField, NamedErrorField:
-			out[fields[i].key] = fields[i].errorValue
+			out[i] = hlog.NamedErr(fields[i].key, fields[i].errorValue)
 		case StringsField:
-			out[fields[i].key] = fields[i].stringsValue
+			out[i] = hlog.Strs(fields[i].key, fields[i].stringsValue)
 		default:
-			out[fields[i].key] = fields[i].interfaceValue
+			out[i] = hlog.Any(fields[i].key, fields[i].interfaceValue)
 		}
 	}
 	return out
@@ -89,42 +94,42 @@ func (l *hlogLogger) Debug(msg string, fields ...Field) {
 	if !l.levelCheck.Check(DebugLevel) {
 		return
 	}
-	l.l.WithFields(l.fields(fields)).Debug(msg)
+	l.l.With(l.fields(fields)...).Debug(msg)
 }
 
 func (l *hlogLogger) Info(msg string, fields ...Field) {
 	if !l.levelCheck.Check(InfoLevel) {
 		return
 	}
-	l.l.WithFields(l.fields(fields)).Info(msg)
+	l.l.With(l.fields(fields)...).Info(msg)
 }
 
 func (l *hlogLogger) Warn(msg string, fields ...Field) {
 	if !l.levelCheck.Check(WarnLevel) {
 		return
 	}
-	l.l.WithFields(l.fields(fields)).Warn(msg)
+	l.l.With(l.fields(fields)...).Warn(msg)
 }
 
 func (l *hlogLogger) Error(msg string, fields ...Field) {
 	if !l.levelCheck.Check(ErrorLevel) {
 		return
 	}
-	l.l.WithFields(l.fields(fields)).Error(msg)
+	l.l.With(l.fields(fields)...).Error(msg)
 }
 
 func (l *hlogLogger) Fatal(msg string, fields ...Field) {
 	if !l.levelCheck.Check(FatalLevel) {
 		return
 	}
-	l.l.WithFields(l.fields(fields)).Fatal(msg)
+	l.l.With(l.fields(fields)...).Fatal(msg)
 }
 
 func (l *hlogLogger) Panic(msg string, fields ...Field) {
 	if !l.levelCheck.Check(PanicLevel) {
 		return
 	}
-	l.l.WithFields(l.fields(fields)).Panic(msg)
+	l.l.With(l.fields(fields)...).Panic(msg)
 }
 
 type hlogLevelLogger struct {
@@ -0,0 +1,58 @@
+package grayhook
+
+import (
+	"errors"
+
+	"github.com/adminhmi/hlog"
+)
+
+// HookName is the name the Graylog hook is registered with for hlog.Configure.
+const HookName = "graylog"
+
+func init() {
+	hlog.RegisterHook(HookName, newHookFromOptions)
+}
+
+// hookOptions are the options accepted in a hlog.HookConfig.
+type hookOptions struct {
+	Addr      string                 `json:"addr"`
+	Async     bool                   `json:"async"`
+	Host      string                 `json:"host"`
+	Level     string                 `json:"level"`
+	Extra     map[string]interface{} `json:"extra"`
+	Blacklist []string               `json:"blacklist"`
+}
+
+func newHookFromOptions(options map[string]interface{}) (hlog.Hook, error) {
+	var opts hookOptions
+	if err := hlog.DecodeHookOptions(options, &opts); err != nil {
+		return nil, err
+	}
+	if opts.Addr == "" {
+		return nil, errors.New("addr is required")
+	}
+	var level hlog.Level
+	if opts.Level != "" {
+		var err error
+		if level, err = hlog.ParseLevel(opts.Level); err != nil {
+			return nil, err
+		}
+	}
+
+	var hook *GraylogHook
+	if opts.Async {
+		hook = NewAsyncGraylogHook(opts.Addr, opts.Extra)
+	} else {
+		hook = NewGraylogHook(opts.Addr, opts.Extra)
+	}
+	if opts.Host != "" {
+		hook.Host = opts.Host
+	}
+	if opts.Level != "" {
+		hook.Level = level
+	}
+	if len(opts.Blacklist) > 0 {
+		hook.Blacklist(opts.Blacklist)
+	}
+	return hook, nil
+}
@@ -209,7 +209,23 @@ func (hook *GraylogHook) sendEntry(entry graylogEntry) {
 		extra["_function"] = entry.Caller.Function
 	}
 
+	if name := entry.LoggerName(); name != "" {
+		extra["_"+hlog.FieldKeyLogger] = name
+	}
+
+	// Trace correlation fields are always sent, as the additional fields
+	// Graylog correlates traces with.
+	trace, hasTrace := hlog.TraceFromFields(entry.Data)
+	if hasTrace {
+		extra["_"+hlog.FieldKeyTraceID] = trace.TraceID
+		extra["_"+hlog.FieldKeySpanID] = trace.SpanID
+		extra["_"+hlog.FieldKeyTraceFlags] = fmt.Sprintf("%02x", trace.Flags)
+	}
+
 	for k, v := range entry.Data {
+		if hasTrace && isTraceField(k) {
+			continue
+		}
 		if !hook.blacklist[k] {
 			extraK := fmt.Sprintf("_%s", k) // "[...] every field you send and prefix with a _ (underscore) will be treated as an additional field."
 			if k == hlog.ErrorKey {
@@ -246,6 +262,10 @@ func (hook *GraylogHook) sendEntry(entry graylogEntry) {
 	}
 }
 
+func isTraceField(k string) bool {
+	return k == hlog.FieldKeyTraceID || k == hlog.FieldKeySpanID || k == hlog.FieldKeyTraceFlags
+}
+
 // Levels returns the available logging levels.
 func (hook *GraylogHook) Levels() []hlog.Level {
 	levels := []hlog.Level{}
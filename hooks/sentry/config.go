@@ -0,0 +1,77 @@
+package sentry
+
+import (
+	"errors"
+	"time"
+
+	"github.com/adminhmi/hlog"
+)
+
+// HookName is the name the Sentry hook is registered with for hlog.Configure.
+const HookName = "sentry"
+
+func init() {
+	hlog.RegisterHook(HookName, newHookFromOptions)
+}
+
+// hookOptions are the options accepted in a hlog.HookConfig.
+type hookOptions struct {
+	DSN         string            `json:"dsn"`
+	Levels      []string          `json:"levels"`
+	Tags        map[string]string `json:"tags"`
+	Async       bool              `json:"async"`
+	Timeout     string            `json:"timeout"`
+	ServerName  string            `json:"server_name"`
+	Environment string            `json:"environment"`
+	Release     string            `json:"release"`
+	Stacktrace  bool              `json:"stacktrace"`
+}
+
+func newHookFromOptions(options map[string]interface{}) (hlog.Hook, error) {
+	var opts hookOptions
+	if err := hlog.DecodeHookOptions(options, &opts); err != nil {
+		return nil, err
+	}
+	if opts.DSN == "" {
+		return nil, errors.New("dsn is required")
+	}
+	levels := []hlog.Level{hlog.PanicLevel, hlog.FatalLevel, hlog.ErrorLevel}
+	if len(opts.Levels) > 0 {
+		levels = levels[:0]
+		for _, l := range opts.Levels {
+			level, err := hlog.ParseLevel(l)
+			if err != nil {
+				return nil, err
+			}
+			levels = append(levels, level)
+		}
+	}
+
+	var hook *SentryHook
+	var err error
+	switch {
+	case opts.Async:
+		hook, err = NewAsyncWithTagsSentryHook(opts.DSN, opts.Tags, levels)
+	default:
+		hook, err = NewWithTagsSentryHook(opts.DSN, opts.Tags, levels)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.Timeout != "" {
+		if hook.Timeout, err = time.ParseDuration(opts.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	if opts.ServerName != "" {
+		hook.SetServerName(opts.ServerName)
+	}
+	if opts.Environment != "" {
+		hook.SetEnvironment(opts.Environment)
+	}
+	if opts.Release != "" {
+		hook.SetRelease(opts.Release)
+	}
+	hook.StacktraceConfiguration.Enable = opts.Stacktrace
+	return hook, nil
+}
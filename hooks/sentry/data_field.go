@@ -135,3 +135,13 @@ func (d *dataField) getUser() (*raven.User, bool) {
 		IP:       ip,
 	}, true
 }
+
+func (d *dataField) getTrace() (hlog.TraceContext, bool) {
+	trace, ok := hlog.TraceFromFields(d.data)
+	if ok {
+		d.omitList[hlog.FieldKeyTraceID] = struct{}{}
+		d.omitList[hlog.FieldKeySpanID] = struct{}{}
+		d.omitList[hlog.FieldKeyTraceFlags] = struct{}{}
+	}
+	return trace, ok
+}
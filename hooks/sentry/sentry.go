@@ -191,6 +191,8 @@ func (hook *SentryHook) Fire(entry *hlog.Entry) error {
 	}
 	if logger, ok := df.getLogger(); ok {
 		packet.Logger = logger
+	} else if name := entry.LoggerName(); name != "" {
+		packet.Logger = name
 	}
 	if serverName, ok := df.getServerName(); ok {
 		packet.ServerName = serverName
@@ -210,6 +212,12 @@ func (hook *SentryHook) Fire(entry *hlog.Entry) error {
 	if user, ok := df.getUser(); ok {
 		packet.Interfaces = append(packet.Interfaces, user)
 	}
+	if trace, ok := df.getTrace(); ok {
+		packet.Interfaces = append(packet.Interfaces, &Contexts{Trace: &TraceContext{
+			TraceID: trace.TraceID,
+			SpanID:  trace.SpanID,
+		}})
+	}
 
 	// set stacktrace data
 	stConfig := &hook.StacktraceConfiguration
@@ -421,3 +429,18 @@ type Value struct {
 func (b *Breadcrumbs) Class() string {
 	return "breadcrumbs"
 }
+
+// Contexts is the contexts interface of a sentry event.
+type Contexts struct {
+	Trace *TraceContext `json:"trace,omitempty"`
+}
+
+// TraceContext correlates a sentry event with a trace.
+type TraceContext struct {
+	TraceID string `json:"trace_id"`
+	SpanID  string `json:"span_id"`
+}
+
+func (c *Contexts) Class() string {
+	return "contexts"
+}
@@ -2,6 +2,7 @@ package sentry
 
 import (
 	"compress/zlib"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -39,6 +40,9 @@ type resultPacket struct {
 	raven.Packet
 	Stacktrace raven.Stacktrace `json:"stacktrace"`
 	Exception  raven.Exception  `json:"exception"`
+	Contexts   struct {
+		Trace TraceContext `json:"trace"`
+	} `json:"contexts"`
 }
 
 func WithTestDSN(t *testing.T, tf func(string, <-chan *resultPacket)) {
@@ -453,3 +457,31 @@ func httptestNewServer(handler func(http.ResponseWriter, *http.Request)) (server
 	)
 	return server, dsn
 }
+
+func TestTraceContext(t *testing.T) {
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []hlog.Level{
+			hlog.ErrorLevel,
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		logger.Hooks.Add(hook)
+
+		ctx, err := hlog.ContextWithTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		logger.WithContext(ctx).Error(message)
+
+		packet := <-pch
+		trace := packet.Contexts.Trace
+		if trace.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || trace.SpanID != "00f067aa0ba902b7" {
+			t.Errorf("unexpected trace context %+v", trace)
+		}
+		if _, ok := packet.Extra[hlog.FieldKeyTraceID]; ok {
+			t.Error("trace_id should not be sent as extra data")
+		}
+	})
+}
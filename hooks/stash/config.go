@@ -0,0 +1,43 @@
+package stash
+
+import (
+	"errors"
+	"net"
+
+	"github.com/adminhmi/hlog"
+)
+
+// HookName is the name the Logstash hook is registered with for hlog.Configure.
+const HookName = "stash"
+
+func init() {
+	hlog.RegisterHook(HookName, newHookFromOptions)
+}
+
+// hookOptions are the options accepted in a hlog.HookConfig.
+type hookOptions struct {
+	Network string      `json:"network"`
+	Addr    string      `json:"addr"`
+	Fields  hlog.Fields `json:"fields"`
+}
+
+func newHookFromOptions(options map[string]interface{}) (hlog.Hook, error) {
+	var opts hookOptions
+	if err := hlog.DecodeHookOptions(options, &opts); err != nil {
+		return nil, err
+	}
+	if opts.Addr == "" {
+		return nil, errors.New("addr is required")
+	}
+	if opts.Network == "" {
+		opts.Network = "tcp"
+	}
+	if opts.Fields == nil {
+		opts.Fields = hlog.Fields{}
+	}
+	conn, err := net.Dial(opts.Network, opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+	return New(conn, DefaultFormatter(opts.Fields)), nil
+}
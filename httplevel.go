@@ -0,0 +1,211 @@
+package hlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LevelHandler returns an http.Handler to view and change the level of the
+// standard logger and its named loggers at runtime. See Logger.LevelHandler.
+func LevelHandler() http.Handler {
+	return std.LevelHandler()
+}
+
+// LevelHandler returns an http.Handler to view and change the level of the
+// logger and of its named loggers at runtime.
+//
+// GET returns the current level as JSON. The named logger to look at is
+// selected with the `logger` query parameter, without it the levels of all
+// the named loggers are listed too.
+//
+// PUT and POST set the level. They take a JSON body like
+//
+//	{"level": "debug", "logger": "db", "ttl": "5m"}
+//
+// or the same keys as form values. `logger` and `ttl` are optional, when a
+// ttl is given the previous level is restored once it expires.
+func (logger *Logger) LevelHandler() http.Handler {
+	return &levelHandler{
+		logger:  logger,
+		reverts: make(map[string]*levelRevert),
+	}
+}
+
+type levelHandler struct {
+	logger *Logger
+
+	mu      sync.Mutex
+	reverts map[string]*levelRevert
+}
+
+// levelRevert is a pending restore of a level changed with a ttl.
+type levelRevert struct {
+	timer *time.Timer
+	level Level
+	// inherited is set when the named logger had no level of its own, the
+	// temporary one is removed instead of pinning the level it inherited.
+	inherited bool
+	at        time.Time
+}
+
+type levelRequest struct {
+	Level  string `json:"level"`
+	Logger string `json:"logger"`
+	TTL    string `json:"ttl"`
+}
+
+type levelResponse struct {
+	Logger   string           `json:"logger,omitempty"`
+	Level    Level            `json:"level"`
+	Previous *Level           `json:"previous,omitempty"`
+	RevertAt *time.Time       `json:"revert_at,omitempty"`
+	Loggers  map[string]Level `json:"loggers,omitempty"`
+}
+
+type levelError struct {
+	Error string `json:"error"`
+}
+
+func (h *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.get(w, r)
+	case http.MethodPut, http.MethodPost:
+		h.set(w, r)
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		h.error(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+}
+
+func (h *levelHandler) get(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("logger")
+	target, err := h.target(name)
+	if err != nil {
+		h.error(w, http.StatusNotFound, err)
+		return
+	}
+
+	resp := levelResponse{Logger: name, Level: target.GetLevel()}
+	h.mu.Lock()
+	if revert, ok := h.reverts[name]; ok {
+		resp.Previous = &revert.level
+		resp.RevertAt = &revert.at
+	}
+	h.mu.Unlock()
+	if tree := h.logger.loadTree(); name == "" && tree != nil {
+		resp.Loggers = make(map[string]Level)
+		for _, child := range tree.names() {
+			resp.Loggers[child] = tree.lookup(child).GetLevel()
+		}
+	}
+	h.write(w, http.StatusOK, resp)
+}
+
+func (h *levelHandler) set(w http.ResponseWriter, r *http.Request) {
+	var req levelRequest
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.error(w, http.StatusBadRequest, fmt.Errorf("invalid json body: %w", err))
+			return
+		}
+	} else {
+		req.Level = r.FormValue("level")
+		req.Logger = r.FormValue("logger")
+		req.TTL = r.FormValue("ttl")
+	}
+
+	level, err := ParseLevel(req.Level)
+	if err != nil {
+		h.error(w, http.StatusBadRequest, err)
+		return
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+			h.error(w, http.StatusBadRequest, fmt.Errorf("invalid ttl %q", req.TTL))
+			return
+		}
+	}
+	target, err := h.target(req.Logger)
+	if err != nil {
+		h.error(w, http.StatusNotFound, err)
+		return
+	}
+
+	h.mu.Lock()
+	previous := target.GetLevel()
+	inherited := false
+	if target.root != nil {
+		_, own := target.tree.rule(target.name)
+		inherited = !own
+	}
+	if revert, ok := h.reverts[req.Logger]; ok {
+		// Keep the level from before the first temporary change.
+		revert.timer.Stop()
+		previous, inherited = revert.level, revert.inherited
+		delete(h.reverts, req.Logger)
+	}
+	target.SetLevel(level)
+	resp := levelResponse{Logger: req.Logger, Level: level, Previous: &previous}
+	if ttl > 0 {
+		revert := &levelRevert{level: previous, inherited: inherited, at: time.Now().Add(ttl)}
+		revert.timer = time.AfterFunc(ttl, func() { h.revert(req.Logger, revert) })
+		h.reverts[req.Logger] = revert
+		resp.RevertAt = &revert.at
+	}
+	h.mu.Unlock()
+
+	h.write(w, http.StatusOK, resp)
+}
+
+func (h *levelHandler) revert(name string, revert *levelRevert) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.reverts[name] != revert {
+		// superseded by a later change
+		return
+	}
+	delete(h.reverts, name)
+	target, err := h.target(name)
+	if err != nil {
+		return
+	}
+	if revert.inherited {
+		target.tree.removeRule(target.name)
+	} else {
+		target.SetLevel(revert.level)
+	}
+}
+
+// target returns the logger itself for an empty name, or the named logger.
+func (h *levelHandler) target(name string) (*Logger, error) {
+	if name == "" {
+		return h.logger, nil
+	}
+	if tree := h.logger.loadTree(); tree != nil {
+		if h.logger.name != "" {
+			name = h.logger.name + "." + name
+		}
+		if child := tree.lookup(name); child != nil {
+			return child, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown logger %q", name)
+}
+
+func (h *levelHandler) write(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.logger.WithError(err).Error("Failed to write level response")
+	}
+}
+
+func (h *levelHandler) error(w http.ResponseWriter, status int, err error) {
+	h.write(w, status, levelError{Error: err.Error()})
+}
@@ -0,0 +1,111 @@
+package hlog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveLevel(t *testing.T, h http.Handler, req *http.Request) (int, map[string]interface{}) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return rec.Code, body
+}
+
+func TestLevelHandler(t *testing.T) {
+	logger := New()
+	logger.Named("db")
+	h := logger.LevelHandler()
+
+	code, body := serveLevel(t, h, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "info", body["level"])
+	assert.Equal(t, map[string]interface{}{"db": "info"}, body["loggers"])
+
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"level":"debug"}`))
+	req.Header.Set("Content-Type", "application/json")
+	code, body = serveLevel(t, h, req)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "debug", body["level"])
+	assert.Equal(t, "info", body["previous"])
+	assert.Equal(t, DebugLevel, logger.GetLevel())
+
+	form := url.Values{"level": {"warn"}, "logger": {"db"}}
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	code, _ = serveLevel(t, h, req)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, WarnLevel, logger.Named("db").GetLevel())
+
+	code, body = serveLevel(t, h, httptest.NewRequest(http.MethodGet, "/?logger=db", nil))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "warning", body["level"])
+}
+
+func TestLevelHandlerErrors(t *testing.T) {
+	h := New().LevelHandler()
+
+	code, _ := serveLevel(t, h, httptest.NewRequest(http.MethodGet, "/?logger=nope", nil))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = serveLevel(t, h, httptest.NewRequest(http.MethodPut, "/?level=loud", nil))
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = serveLevel(t, h, httptest.NewRequest(http.MethodPut, "/?level=debug&ttl=soon", nil))
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = serveLevel(t, h, httptest.NewRequest(http.MethodDelete, "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
+
+func TestLevelHandlerTTL(t *testing.T) {
+	logger := New()
+	h := logger.LevelHandler()
+
+	code, body := serveLevel(t, h, httptest.NewRequest(http.MethodPut, "/?level=trace&ttl=20ms", nil))
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotNil(t, body["revert_at"])
+	assert.Equal(t, TraceLevel, logger.GetLevel())
+
+	// a second temporary change keeps the original level to revert to
+	code, body = serveLevel(t, h, httptest.NewRequest(http.MethodPut, "/?level=debug&ttl=20ms", nil))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "info", body["previous"])
+
+	assert.Eventually(t, func() bool {
+		return logger.GetLevel() == InfoLevel
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestLevelHandlerTTLNamed(t *testing.T) {
+	logger := New()
+	db := logger.Named("db")
+	cache := logger.Named("cache")
+	cache.SetLevel(ErrorLevel)
+	h := logger.LevelHandler()
+
+	code, _ := serveLevel(t, h, httptest.NewRequest(http.MethodPut, "/?level=trace&logger=db&ttl=20ms", nil))
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serveLevel(t, h, httptest.NewRequest(http.MethodPut, "/?level=trace&logger=cache&ttl=20ms", nil))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, TraceLevel, db.GetLevel())
+	assert.Equal(t, TraceLevel, cache.GetLevel())
+
+	assert.Eventually(t, func() bool {
+		return db.GetLevel() == InfoLevel && cache.GetLevel() == ErrorLevel
+	}, time.Second, 5*time.Millisecond)
+
+	// db follows the root again, cache keeps its own level
+	logger.SetLevel(WarnLevel)
+	assert.Equal(t, WarnLevel, db.GetLevel())
+	assert.Equal(t, ErrorLevel, cache.GetLevel())
+}
@@ -0,0 +1,256 @@
+package hlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"runtime"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString writes s as a quoted JSON string, escaping it the same
+// way encoding/json does.
+func appendJSONString(b *bytes.Buffer, s string, escapeHTML bool) {
+	b.WriteByte('"')
+	appendJSONText(b, s, escapeHTML)
+	b.WriteByte('"')
+}
+
+// appendJSONBytes is appendJSONString for text formatted to a scratch
+// buffer, only converted to a string when it has to be escaped.
+func appendJSONBytes(b *bytes.Buffer, s []byte, escapeHTML bool) {
+	for _, c := range s {
+		if c < 0x20 || c >= utf8.RuneSelf || c == '"' || c == '\\' || (escapeHTML && (c == '<' || c == '>' || c == '&')) {
+			appendJSONString(b, string(s), escapeHTML)
+			return
+		}
+	}
+	b.WriteByte('"')
+	b.Write(s)
+	b.WriteByte('"')
+}
+
+// appendJSONText writes s escaped, without the quotes.
+func appendJSONText(b *bytes.Buffer, s string, escapeHTML bool) {
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && (!escapeHTML || (c != '<' && c != '>' && c != '&')) {
+				i++
+				continue
+			}
+			b.WriteString(s[start:i])
+			switch c {
+			case '"', '\\':
+				b.WriteByte('\\')
+				b.WriteByte(c)
+			case '\n':
+				b.WriteString(`\n`)
+			case '\r':
+				b.WriteString(`\r`)
+			case '\t':
+				b.WriteString(`\t`)
+			default:
+				b.WriteString(`\u00`)
+				b.WriteByte(hexDigits[c>>4])
+				b.WriteByte(hexDigits[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b.WriteString(s[start:i])
+			b.WriteString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 and U+2029 are valid JSON but break JSONP.
+		if r == '\u2028' || r == '\u2029' {
+			b.WriteString(s[start:i])
+			b.WriteString(`\u202`)
+			b.WriteByte(hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b.WriteString(s[start:])
+}
+
+// appendJSONFloat writes f using the same format as encoding/json.
+func appendJSONFloat(b *bytes.Buffer, f float64) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		// not representable in JSON
+		var scratch [8]byte
+		b.WriteByte('"')
+		b.Write(strconv.AppendFloat(scratch[:0], f, 'g', -1, 64))
+		b.WriteByte('"')
+		return
+	}
+	var scratch [64]byte
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	out := strconv.AppendFloat(scratch[:0], f, format, -1, 64)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(out)
+		if n >= 4 && out[n-4] == 'e' && out[n-3] == '-' && out[n-2] == '0' {
+			out[n-2] = out[n-1]
+			out = out[:n-1]
+		}
+	}
+	b.Write(out)
+}
+
+// jsonEncoder writes JSON values to a buffer. The json.Encoder used for the
+// values of other types than the usual ones is only created when needed.
+type jsonEncoder struct {
+	b          *bytes.Buffer
+	escapeHTML bool
+	encoder    *json.Encoder
+}
+
+// encode writes v with encoding/json.
+func (e *jsonEncoder) encode(v interface{}) error {
+	if e.encoder == nil {
+		e.encoder = json.NewEncoder(e.b)
+		e.encoder.SetEscapeHTML(e.escapeHTML)
+	}
+	if err := e.encoder.Encode(v); err != nil {
+		return err
+	}
+	// Encode terminates each value with a newline, drop it.
+	e.b.Truncate(e.b.Len() - 1)
+	return nil
+}
+
+// writeObject writes the boxed fields, sorted by key like encoding/json
+// does for maps, followed by the typed fields in the order they were added.
+// The boxed fields are sorted in place.
+func (e *jsonEncoder) writeObject(data, fields []Field) error {
+	sortFields(data)
+	b := e.b
+	b.WriteByte('{')
+	for i := range data {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		appendJSONString(b, data[i].key, e.escapeHTML)
+		b.WriteByte(':')
+		if nested, ok := data[i].interfaceValue.(*jsonObject); ok {
+			if err := e.writeObject(nested.data, nested.fields); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := e.appendField(data[i]); err != nil {
+			return err
+		}
+	}
+	for i := range fields {
+		if i > 0 || len(data) > 0 {
+			b.WriteByte(',')
+		}
+		appendJSONString(b, fields[i].key, e.escapeHTML)
+		b.WriteByte(':')
+		if err := e.appendField(fields[i]); err != nil {
+			return err
+		}
+	}
+	b.WriteByte('}')
+	return nil
+}
+
+// appendField writes the value of a field without boxing it. Only the boxed
+// values of other types than the usual ones go through encoding/json.
+func (e *jsonEncoder) appendField(f Field) error {
+	var scratch [64]byte
+	b, escapeHTML := e.b, e.escapeHTML
+	switch f.kind {
+	case StringField, ByteStringField:
+		appendJSONString(b, f.stringValue, escapeHTML)
+	case StringsField:
+		if f.stringsValue == nil {
+			b.WriteString("null")
+			return nil
+		}
+		b.WriteByte('[')
+		for i, s := range f.stringsValue {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			appendJSONString(b, s, escapeHTML)
+		}
+		b.WriteByte(']')
+	case IntField:
+		b.Write(strconv.AppendInt(scratch[:0], f.intValue, 10))
+	case UintField:
+		b.Write(strconv.AppendUint(scratch[:0], uint64(f.intValue), 10))
+	case FloatField:
+		appendJSONFloat(b, math.Float64frombits(uint64(f.intValue)))
+	case BoolField:
+		b.Write(strconv.AppendBool(scratch[:0], f.intValue != 0))
+	case DurationField:
+		appendJSONString(b, time.Duration(f.intValue).String(), escapeHTML)
+	case TimeField:
+		b.WriteByte('"')
+		b.Write(f.time().AppendFormat(scratch[:0], time.RFC3339Nano))
+		b.WriteByte('"')
+	case ErrorField:
+		if s, ok := f.text(); ok {
+			appendJSONString(b, s, escapeHTML)
+		} else {
+			b.WriteString("null")
+		}
+	case timestampField:
+		t := f.interfaceValue.(*time.Time)
+		appendJSONBytes(b, t.AppendFormat(scratch[:0], f.stringValue), escapeHTML)
+	case callerFileField:
+		frame := f.interfaceValue.(*runtime.Frame)
+		b.WriteByte('"')
+		appendJSONText(b, frame.File, escapeHTML)
+		b.WriteByte(':')
+		b.Write(strconv.AppendInt(scratch[:0], int64(frame.Line), 10))
+		b.WriteByte('"')
+	default:
+		return e.appendValue(f.interfaceValue)
+	}
+	return nil
+}
+
+// appendValue writes a boxed value, as encoding/json would.
+func (e *jsonEncoder) appendValue(v interface{}) error {
+	var scratch [64]byte
+	switch v := v.(type) {
+	case nil:
+		e.b.WriteString("null")
+	case string:
+		appendJSONString(e.b, v, e.escapeHTML)
+	case int:
+		e.b.Write(strconv.AppendInt(scratch[:0], int64(v), 10))
+	case int64:
+		e.b.Write(strconv.AppendInt(scratch[:0], v, 10))
+	case bool:
+		e.b.Write(strconv.AppendBool(scratch[:0], v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			// let encoding/json fail
+			return e.encode(v)
+		}
+		appendJSONFloat(e.b, v)
+	default:
+		return e.encode(v)
+	}
+	return nil
+}
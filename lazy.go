@@ -0,0 +1,62 @@
+package hlog
+
+// maximumValuerDepth bounds the resolution of valuers returning valuers.
+const maximumValuerDepth = 8
+
+// Valuer is implemented by field values computed only when the entry is
+// logged. The value is resolved once, after the level check and before the
+// hooks and the formatter run, so expensive values cost nothing when their
+// level is disabled.
+type Valuer interface {
+	LogValue() interface{}
+}
+
+// Lazy is a Valuer calling a function, e.g.:
+//
+//	logger.WithField("state", hlog.Lazy(func() interface{} {
+//		return dumpState()
+//	})).Debug("tick")
+type Lazy func() interface{}
+
+// LogValue calls the function.
+func (f Lazy) LogValue() interface{} {
+	return f()
+}
+
+// resolve returns the value of v, resolving it if it is a Valuer.
+func resolve(v interface{}) interface{} {
+	for i := 0; i < maximumValuerDepth; i++ {
+		valuer, ok := v.(Valuer)
+		if !ok {
+			break
+		}
+		v = valuer.LogValue()
+	}
+	return v
+}
+
+// resolveValuers replaces the Valuer values of the boxed and typed fields by
+// their value. It must only be called on entries owning their Data map.
+func (entry *Entry) resolveValuers() {
+	for k, v := range entry.Data {
+		if _, ok := v.(Valuer); ok {
+			entry.Data[k] = resolve(v)
+		}
+	}
+	copied := false
+	for i := range entry.fields {
+		if entry.fields[i].kind != InterfaceField {
+			continue
+		}
+		if _, ok := entry.fields[i].interfaceValue.(Valuer); !ok {
+			continue
+		}
+		// The typed fields are shared with the entry the logged one was
+		// created from.
+		if !copied {
+			entry.fields = append([]Field(nil), entry.fields...)
+			copied = true
+		}
+		entry.fields[i].interfaceValue = resolve(entry.fields[i].interfaceValue)
+	}
+}
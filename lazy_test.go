@@ -0,0 +1,62 @@
+package hlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stateValuer struct {
+	calls *int
+}
+
+func (v stateValuer) LogValue() interface{} {
+	*v.calls++
+	return map[string]int{"calls": *v.calls}
+}
+
+func TestLazy(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+	hook := new(fieldsHook)
+	logger.AddHook(hook)
+
+	calls := 0
+	entry := logger.WithField("dump", Lazy(func() interface{} {
+		calls++
+		return "expensive"
+	}))
+	entry.Debug("disabled")
+	assert.Equal(t, 0, calls)
+	assert.Empty(t, buffer.String())
+
+	entry.Info("enabled")
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "expensive", hook.data["dump"])
+	var fields Fields
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &fields))
+	assert.Equal(t, "expensive", fields["dump"])
+	assert.NotContains(t, fields, FieldKeyHmiLogError)
+	assert.IsType(t, Lazy(nil), entry.Data["dump"])
+}
+
+func TestValuerTypedField(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &TextFormatter{DisableTimestamp: true}
+
+	calls := 0
+	entry := logger.With(Any("state", stateValuer{&calls}), Str("k", "v"))
+	entry.Info("first")
+	entry.Info("second")
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, "level=info msg=first k=v state=map[calls:1]\nlevel=info msg=second k=v state=map[calls:2]\n", buffer.String())
+	_, ok := entry.Fields()[0].Value().(Valuer)
+	assert.True(t, ok)
+}
@@ -0,0 +1,279 @@
+package hlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LevelPattern detects the level of a line. When the pattern has a
+// submatch, it is parsed as the level, otherwise Level is used.
+type LevelPattern struct {
+	Pattern *regexp.Regexp
+	Level   Level
+}
+
+// DefaultLevelPatterns returns the patterns used by line writers without
+// patterns of their own. They detect `level=warn` like keys and upper case
+// level names such as `ERROR` or `[WARN]`.
+func DefaultLevelPatterns() []LevelPattern {
+	return []LevelPattern{
+		{Pattern: regexp.MustCompile(`(?i)\b(?:level|lvl|severity)["']?\s*[=:]\s*["']?([a-z]+)`)},
+		{Pattern: regexp.MustCompile(`\b(TRACE|DEBUG|INFO|WARN|WARNING|ERROR|ERR|FATAL|CRITICAL|CRIT|PANIC)\b`)},
+	}
+}
+
+// LineWriterOptions configures a LineWriter.
+type LineWriterOptions struct {
+	// Patterns detect the level of the lines, the first matching one wins.
+	// DefaultLevelPatterns are used when nil, give an empty slice to log
+	// every line at the level of the writer.
+	Patterns []LevelPattern
+	// DisableJSON disables the parsing of the lines made of a JSON object.
+	DisableJSON bool
+	// DisableLogfmt disables the parsing of the lines made of key=value
+	// pairs.
+	DisableLogfmt bool
+	// MaxLineSize splits the lines longer than that many bytes, they are
+	// unbounded by default.
+	MaxLineSize int
+}
+
+// LineWriter is an io.WriteCloser logging each line written to it, for
+// instance the output of a child process. The level of each line is
+// detected with patterns, falling back to the level of the writer. The lines
+// made of a JSON object or of logfmt key=value pairs are parsed back into
+// fields, their `level`, `msg` and `time` keys giving the level, message and
+// time of the entry.
+//
+// Lines are logged as soon as their newline is written, Close logs the last
+// line when it has none. Detected FatalLevel and PanicLevel lines are logged
+// without exiting nor panicking.
+type LineWriter struct {
+	entry *Entry
+	level Level
+	opts  LineWriterOptions
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// LineWriter returns a LineWriter logging the lines at level unless another
+// level is detected.
+func (logger *Logger) LineWriter(level Level, opts LineWriterOptions) *LineWriter {
+	return NewEntry(logger).LineWriter(level, opts)
+}
+
+// LineWriter returns a LineWriter logging the lines with the fields of the
+// entry, see Logger.LineWriter.
+func (entry *Entry) LineWriter(level Level, opts LineWriterOptions) *LineWriter {
+	if opts.Patterns == nil {
+		opts.Patterns = DefaultLevelPatterns()
+	}
+	return &LineWriter{entry: entry, level: level, opts: opts}
+}
+
+// Write logs the complete lines of p and buffers the rest.
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.buf.Write(p)
+			break
+		}
+		if w.buf.Len() > 0 {
+			w.buf.Write(p[:i])
+			w.splitLine(w.buf.Bytes())
+			w.buf.Reset()
+		} else {
+			w.splitLine(p[:i])
+		}
+		p = p[i+1:]
+	}
+	for w.opts.MaxLineSize > 0 && w.buf.Len() >= w.opts.MaxLineSize {
+		w.logLine(w.buf.Next(w.opts.MaxLineSize))
+	}
+	return n, nil
+}
+
+// splitLine logs a line in chunks of MaxLineSize bytes.
+func (w *LineWriter) splitLine(b []byte) {
+	for w.opts.MaxLineSize > 0 && len(b) > w.opts.MaxLineSize {
+		w.logLine(b[:w.opts.MaxLineSize])
+		b = b[w.opts.MaxLineSize:]
+	}
+	w.logLine(b)
+}
+
+// Close logs the last line if it wasn't terminated by a newline.
+func (w *LineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.buf.Len() > 0 {
+		w.splitLine(w.buf.Bytes())
+		w.buf.Reset()
+	}
+	return nil
+}
+
+func (w *LineWriter) logLine(b []byte) {
+	line := string(bytes.TrimSuffix(b, []byte{'\r'}))
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	var fields Fields
+	if !w.opts.DisableJSON {
+		fields = parseJSONLine(line)
+	}
+	if fields == nil && !w.opts.DisableLogfmt {
+		fields = parseLogfmt(line)
+	}
+	if fields == nil {
+		level := w.detectLevel(line)
+		if w.entry.Logger.IsLevelEnabled(level) {
+			w.entry.emit(level, line)
+		}
+		return
+	}
+
+	msg, _ := takeString(fields, "msg", "message")
+	name, _ := takeString(fields, "level", "lvl", "severity")
+	level, ok := parseLevelName(name)
+	if !ok {
+		level = w.detectLevel(msg)
+	}
+	if !w.entry.Logger.IsLevelEnabled(level) {
+		return
+	}
+	entry := w.entry.WithFields(fields)
+	if s, found := takeString(fields, "time", "ts", "timestamp"); found {
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			entry.Time = t
+			delete(entry.Data, "time")
+			delete(entry.Data, "ts")
+			delete(entry.Data, "timestamp")
+		}
+	}
+	delete(entry.Data, "msg")
+	delete(entry.Data, "message")
+	delete(entry.Data, "level")
+	delete(entry.Data, "lvl")
+	delete(entry.Data, "severity")
+	entry.emit(level, msg)
+}
+
+// detectLevel returns the level found by the patterns, or the level of the
+// writer.
+func (w *LineWriter) detectLevel(line string) Level {
+	for _, p := range w.opts.Patterns {
+		m := p.Pattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if len(m) < 2 {
+			return p.Level
+		}
+		if level, ok := parseLevelName(m[1]); ok {
+			return level
+		}
+	}
+	return w.level
+}
+
+// parseLevelName parses a level name, including the usual aliases.
+func parseLevelName(s string) (Level, bool) {
+	if level, err := ParseLevel(s); err == nil {
+		return level, true
+	}
+	switch strings.ToLower(s) {
+	case "err":
+		return ErrorLevel, true
+	case "crit", "critical":
+		return FatalLevel, true
+	case "dbg":
+		return DebugLevel, true
+	}
+	return 0, false
+}
+
+// takeString returns the first of the keys found in fields, as a string.
+func takeString(fields Fields, keys ...string) (string, bool) {
+	for _, k := range keys {
+		if v, ok := fields[k]; ok {
+			if s, ok := v.(string); ok {
+				return s, true
+			}
+		}
+	}
+	return "", false
+}
+
+// parseJSONLine returns the fields of a line made of a JSON object, or nil.
+func parseJSONLine(line string) Fields {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "{") {
+		return nil
+	}
+	var fields Fields
+	if err := json.Unmarshal([]byte(trimmed), &fields); err != nil {
+		return nil
+	}
+	return fields
+}
+
+// parseLogfmt returns the fields of a line made only of key=value pairs,
+// values being bare or double quoted, or nil.
+func parseLogfmt(line string) Fields {
+	fields := Fields{}
+	for i := 0; i < len(line); {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' && line[i] != '"' {
+			i++
+		}
+		if i == start || i == len(line) || line[i] != '=' {
+			return nil
+		}
+		key := line[start:i]
+		i++
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil
+			}
+			value, err := strconv.Unquote(line[i : end+1])
+			if err != nil {
+				return nil
+			}
+			fields[key] = value
+			i = end + 1
+			continue
+		}
+		start = i
+		for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+			i++
+		}
+		fields[key] = line[start:i]
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
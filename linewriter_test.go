@@ -0,0 +1,184 @@
+package hlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineWriterLevels(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+
+	w := logger.WithField("app", "db").LineWriter(InfoLevel, LineWriterOptions{})
+	_, err := w.Write([]byte("starting\n2024/01/02 ERROR: disk full\r\nlevel=warn is not logfmt here\n[WARN] slow\n"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("FATAL: out of memory\n"))
+	require.NoError(t, err)
+
+	lines := decodeLines(t, &buffer)
+	require.Len(t, lines, 5)
+	assert.Equal(t, "info", lines[0]["level"])
+	assert.Equal(t, "starting", lines[0]["msg"])
+	assert.Equal(t, "db", lines[0]["app"])
+	assert.Equal(t, "error", lines[1]["level"])
+	assert.Equal(t, "2024/01/02 ERROR: disk full", lines[1]["msg"])
+	assert.Equal(t, "warning", lines[2]["level"])
+	assert.Equal(t, "warning", lines[3]["level"])
+	// Logged without exiting.
+	assert.Equal(t, "fatal", lines[4]["level"])
+}
+
+func TestLineWriterCustomPatterns(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+
+	w := logger.LineWriter(DebugLevel, LineWriterOptions{Patterns: []LevelPattern{
+		{Pattern: regexp.MustCompile(`^E\d+`), Level: ErrorLevel},
+	}})
+	_, err := w.Write([]byte("E0102 failed\nERROR ignored\n"))
+	require.NoError(t, err)
+
+	lines := decodeLines(t, &buffer)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "error", lines[0]["level"])
+	assert.Equal(t, "E0102 failed", lines[0]["msg"])
+	buffer.Reset()
+
+	// The second line is logged at DebugLevel, below the level of the logger.
+	logger.SetLevel(DebugLevel)
+	_, err = w.Write([]byte("ERROR ignored\n"))
+	require.NoError(t, err)
+	lines = decodeLines(t, &buffer)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "debug", lines[0]["level"])
+}
+
+func TestLineWriterStructuredLines(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+
+	w := logger.LineWriter(InfoLevel, LineWriterOptions{})
+	_, err := w.Write([]byte(`{"level":"error","msg":"query failed","time":"2024-01-02T03:04:05Z","rows":3}` + "\n"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte(`ts=2024-01-02T03:04:05.5Z lvl=warn msg="slow \"query\"" took=250ms` + "\n"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("user=alice action=login\n"))
+	require.NoError(t, err)
+
+	lines := decodeLines(t, &buffer)
+	require.Len(t, lines, 3)
+
+	assert.Equal(t, "error", lines[0]["level"])
+	assert.Equal(t, "query failed", lines[0]["msg"])
+	assert.Equal(t, "2024-01-02T03:04:05Z", lines[0]["time"])
+	assert.Equal(t, float64(3), lines[0]["rows"])
+	assert.NotContains(t, lines[0], "fields.level")
+	assert.NotContains(t, lines[0], "fields.msg")
+
+	assert.Equal(t, "warning", lines[1]["level"])
+	assert.Equal(t, `slow "query"`, lines[1]["msg"])
+	assert.Equal(t, "250ms", lines[1]["took"])
+	assert.NotContains(t, lines[1], "ts")
+	assert.NotContains(t, lines[1], "lvl")
+	ts, err := time.Parse(time.RFC3339, lines[1]["time"].(string))
+	require.NoError(t, err)
+	assert.True(t, ts.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)))
+
+	assert.Equal(t, "info", lines[2]["level"])
+	assert.Equal(t, "", lines[2]["msg"])
+	assert.Equal(t, "alice", lines[2]["user"])
+	assert.Equal(t, "login", lines[2]["action"])
+	buffer.Reset()
+
+	w = logger.LineWriter(InfoLevel, LineWriterOptions{DisableJSON: true, DisableLogfmt: true})
+	_, err = w.Write([]byte("user=alice level=error\n"))
+	require.NoError(t, err)
+	lines = decodeLines(t, &buffer)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "error", lines[0]["level"])
+	assert.Equal(t, "user=alice level=error", lines[0]["msg"])
+}
+
+func TestParseLogfmt(t *testing.T) {
+	assert.Equal(t, Fields{"a": "1", "b": "x y", "c": ""}, parseLogfmt(`a=1 b="x y"  c=`))
+	assert.Nil(t, parseLogfmt("plain text"))
+	assert.Nil(t, parseLogfmt("a=1 and more"))
+	assert.Nil(t, parseLogfmt(`a="unterminated`))
+	assert.Nil(t, parseLogfmt(""))
+}
+
+func TestLineWriterLongLinesAndClose(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+
+	long := strings.Repeat("x", 200*1024)
+	w := logger.LineWriter(InfoLevel, LineWriterOptions{})
+	for i := 0; i < len(long); i += 4096 {
+		_, err := w.Write([]byte(long[i : i+4096]))
+		require.NoError(t, err)
+	}
+	_, err := w.Write([]byte("\npartial"))
+	require.NoError(t, err)
+
+	lines := decodeLines(t, &buffer)
+	require.Len(t, lines, 1)
+	assert.Equal(t, long, lines[0]["msg"])
+	buffer.Reset()
+
+	require.NoError(t, w.Close())
+	lines = decodeLines(t, &buffer)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "partial", lines[0]["msg"])
+	buffer.Reset()
+
+	w = logger.LineWriter(InfoLevel, LineWriterOptions{MaxLineSize: 4})
+	_, err = w.Write([]byte("abcdefghij\nklmnop"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	var msgs []interface{}
+	for _, fields := range decodeLines(t, &buffer) {
+		msgs = append(msgs, fields["msg"])
+	}
+	assert.Equal(t, []interface{}{"abcd", "efgh", "ij", "klmn", "op"}, msgs)
+}
+
+func TestWriterLevelLongLines(t *testing.T) {
+	reader, out := io.Pipe()
+	logger := New()
+	logger.Out = out
+	logger.Formatter = new(JSONFormatter)
+
+	long := strings.Repeat("x", 100*1024)
+	w := logger.WriterLevel(WarnLevel)
+	go func() {
+		w.Write([]byte(long + "\nlast"))
+		w.Close()
+	}()
+
+	br := bufio.NewReader(reader)
+	for _, msg := range []string{long, "last"} {
+		line, err := br.ReadBytes('\n')
+		require.NoError(t, err)
+		var fields Fields
+		require.NoError(t, json.Unmarshal(line, &fields))
+		assert.Equal(t, msg, fields["msg"])
+		assert.Equal(t, "warning", fields["level"])
+	}
+}
@@ -28,7 +28,39 @@ type Logger struct {
 
 	ExitFunc exitFunc
 
+	// ErrorHandler is called with the errors returned by hooks, they are
+	// printed on stderr when it is nil.
+	ErrorHandler HookErrorHandler
+
 	BufferPool BufferPool
+
+	// name of a child logger created with Named, empty for root loggers
+	name string
+	// parent and root are only set on child loggers
+	parent *Logger
+	root   *Logger
+	// tree is shared by a root logger and all of its named children
+	tree *loggerTree
+	// sampler drops entries past a rate, see SetSampler
+	sampler *Sampler
+	// deduper swallows repeated entries, see SetDeduper
+	deduper *Deduper
+	// extractors add fields from the entry context, see AddContextExtractor
+	extractors []ContextExtractor
+	// hookPolicy and breaker handle failing hooks, see SetHookErrorPolicy
+	hookPolicy HookErrorPolicy
+	breaker    hookBreaker
+	// stackLevel is the least severe level getting a stack trace when
+	// reportStack is set, see SetStackLevel
+	stackLevel  Level
+	reportStack bool
+	// callerSkip is the number of frames skipped past the hlog ones when
+	// reporting the caller, see SetCallerSkip
+	callerSkip int
+	// redactor masks sensitive data, see SetRedactor
+	redactor *Redactor
+	// processors transform entries before they are logged, see AddProcessor
+	processors []Processor
 }
 
 type exitFunc func(int)
@@ -74,7 +106,7 @@ func (logger *Logger) newEntry() *Entry {
 }
 
 func (logger *Logger) releaseEntry(entry *Entry) {
-	entry.Data = map[string]interface{}{}
+	entry.reset()
 	logger.entryPool.Put(entry)
 }
 
@@ -90,12 +122,27 @@ func (logger *Logger) WithFields(fields Fields) *Entry {
 	return entry.WithFields(fields)
 }
 
+// With creates an entry from the logger and adds typed fields to it.
+func (logger *Logger) With(fields ...Field) *Entry {
+	// The Data map is shared with the returned entry, so the entry can't
+	// come from the pool.
+	return (&Entry{Logger: logger, Data: Fields{}}).With(fields...)
+}
+
 func (logger *Logger) WithError(err error) *Entry {
 	entry := logger.newEntry()
 	defer logger.releaseEntry(entry)
 	return entry.WithError(err)
 }
 
+// WithStack creates an entry from the logger and adds the stack of the
+// caller to it.
+func (logger *Logger) WithStack() *Entry {
+	entry := logger.newEntry()
+	defer logger.releaseEntry(entry)
+	return entry.WithStack()
+}
+
 // Add a context to the log entry.
 func (logger *Logger) WithContext(ctx context.Context) *Entry {
 	entry := logger.newEntry()
@@ -298,8 +345,11 @@ func (logger *Logger) Panicln(args ...interface{}) {
 	logger.Logln(PanicLevel, args...)
 }
 
+// Exit runs the exit handlers and shuts down the logger, waiting at most
+// ShutdownTimeout, before calling ExitFunc.
 func (logger *Logger) Exit(code int) {
 	runHandlers()
+	logger.shutdownForExit()
 	if logger.ExitFunc == nil {
 		logger.ExitFunc = os.Exit
 	}
@@ -307,16 +357,24 @@ func (logger *Logger) Exit(code int) {
 }
 
 func (logger *Logger) SetNoLock() {
-	logger.mu.Disable()
+	logger.lock().Disable()
 }
 
 func (logger *Logger) level() Level {
 	return Level(atomic.LoadUint32((*uint32)(&logger.Level)))
 }
 
-// SetLevel sets the logger level.
+// SetLevel sets the logger level. On a named logger this overrides the level
+// given to it by SetLevels, children without a level of their own follow it.
 func (logger *Logger) SetLevel(level Level) {
+	if logger.root != nil {
+		logger.tree.setRule(logger.name, level)
+		return
+	}
 	atomic.StoreUint32((*uint32)(&logger.Level), uint32(level))
+	if tree := logger.loadTree(); tree != nil {
+		tree.refresh()
+	}
 }
 
 // GetLevel returns the logger level.
@@ -326,9 +384,12 @@ func (logger *Logger) GetLevel() Level {
 
 // AddHook adds a hooks to the logger hooks.
 func (logger *Logger) AddHook(hook Hook) {
-	logger.mu.Lock()
-	defer logger.mu.Unlock()
+	logger.lock().Lock()
 	logger.Hooks.Add(hook)
+	logger.lock().Unlock()
+	logger.eachChild(func(child *Logger) {
+		child.AddHook(hook)
+	})
 }
 
 // IsLevelEnabled checks if the log level of the logger is greater than the level param
@@ -338,36 +399,68 @@ func (logger *Logger) IsLevelEnabled(level Level) bool {
 
 // SetFormatter sets the logger formatter.
 func (logger *Logger) SetFormatter(formatter Formatter) {
-	logger.mu.Lock()
-	defer logger.mu.Unlock()
+	logger.lock().Lock()
 	logger.Formatter = formatter
+	logger.lock().Unlock()
+	logger.eachChild(func(child *Logger) {
+		child.SetFormatter(formatter)
+	})
 }
 
 // SetOutput sets the logger output.
 func (logger *Logger) SetOutput(output io.Writer) {
-	logger.mu.Lock()
-	defer logger.mu.Unlock()
+	logger.lock().Lock()
 	logger.Out = output
+	logger.lock().Unlock()
+	logger.eachChild(func(child *Logger) {
+		child.SetOutput(output)
+	})
 }
 
 func (logger *Logger) SetReportCaller(reportCaller bool) {
-	logger.mu.Lock()
-	defer logger.mu.Unlock()
+	logger.lock().Lock()
 	logger.ReportCaller = reportCaller
+	logger.lock().Unlock()
+	logger.eachChild(func(child *Logger) {
+		child.SetReportCaller(reportCaller)
+	})
 }
 
 // ReplaceHooks replaces the logger hooks and returns the old ones
 func (logger *Logger) ReplaceHooks(hooks LevelHooks) LevelHooks {
-	logger.mu.Lock()
+	logger.lock().Lock()
 	oldHooks := logger.Hooks
 	logger.Hooks = hooks
-	logger.mu.Unlock()
+	logger.lock().Unlock()
+	logger.eachChild(func(child *Logger) {
+		child.ReplaceHooks(hooks.copy())
+	})
 	return oldHooks
 }
 
 // SetBufferPool sets the logger buffer pool.
 func (logger *Logger) SetBufferPool(pool BufferPool) {
-	logger.mu.Lock()
-	defer logger.mu.Unlock()
+	logger.lock().Lock()
 	logger.BufferPool = pool
+	logger.lock().Unlock()
+	logger.eachChild(func(child *Logger) {
+		child.SetBufferPool(pool)
+	})
+}
+
+// lock returns the mutex of the logger. Named loggers share the one of their
+// root so that writes to a common Out never interleave.
+func (logger *Logger) lock() *MutexWrap {
+	if logger.root != nil {
+		return &logger.root.mu
+	}
+	return &logger.mu
+}
+
+// hookBreaker returns the failing hooks tracker shared by the logger tree.
+func (logger *Logger) hookBreaker() *hookBreaker {
+	if logger.root != nil {
+		return &logger.root.breaker
+	}
+	return &logger.breaker
 }
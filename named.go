@@ -0,0 +1,280 @@
+package hlog
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// FieldKeyLogger is the key under which the name of a named logger is
+// emitted by the default formatters.
+const FieldKeyLogger = "logger"
+
+// loggerTree keeps track of the named children of a root logger and of the
+// level rules applied to them.
+type loggerTree struct {
+	mu       sync.Mutex
+	root     *Logger
+	children map[string]*Logger
+	rules    []levelRule
+}
+
+type levelRule struct {
+	pattern string
+	level   Level
+}
+
+// Named returns a child logger called name, or parent.name if the logger is
+// itself named. The child starts with the Out, Formatter, Hooks, ReportCaller,
+// ExitFunc, ErrorHandler, BufferPool and other settings of the logger and
+// follows later changes made through its setters, but carries its own name
+// and level. Calling Named twice with the same name returns the same child.
+func (logger *Logger) Named(name string) *Logger {
+	if logger.name != "" {
+		name = logger.name + "." + name
+	}
+	tree := logger.loadTree()
+	if tree == nil {
+		tree = logger.initTree()
+	}
+
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	if child, ok := tree.children[name]; ok {
+		return child
+	}
+
+	root := logger.root
+	if root == nil {
+		root = logger
+	}
+	logger.lock().Lock()
+	child := &Logger{
+		Out:          logger.Out,
+		Hooks:        logger.Hooks.copy(),
+		Formatter:    logger.Formatter,
+		ReportCaller: logger.ReportCaller,
+		ExitFunc:     logger.ExitFunc,
+		ErrorHandler: logger.ErrorHandler,
+		BufferPool:   logger.BufferPool,
+		sampler:      logger.sampler,
+		deduper:      logger.deduper,
+		extractors:   logger.extractors,
+		hookPolicy:   logger.hookPolicy,
+		stackLevel:   logger.stackLevel,
+		reportStack:  logger.reportStack,
+		callerSkip:   logger.callerSkip,
+		redactor:     logger.redactor,
+		processors:   logger.processors,
+		name:         name,
+		parent:       logger,
+		root:         root,
+		tree:         tree,
+	}
+	logger.lock().Unlock()
+	child.Level = tree.resolve(name)
+	tree.children[name] = child
+	return child
+}
+
+// Name returns the name of the logger, empty for a root logger.
+func (logger *Logger) Name() string {
+	return logger.name
+}
+
+// SetLevels sets the levels of the named loggers sharing this logger's root
+// from a comma separated list of pattern=level pairs, e.g.
+// "db.*=debug,http=warn". Patterns are matched with path.Match, and a
+// logger without a matching rule uses the level of its closest named
+// ancestor that has one, then the level of the root. An entry without a
+// pattern sets the level of the root. The rules replace any level set before
+// on the named loggers.
+func (logger *Logger) SetLevels(spec string) error {
+	levels, err := parseLevels(spec)
+	if err != nil {
+		return err
+	}
+	logger.applyLevels(levels)
+	return nil
+}
+
+// levelSpec is a parsed SetLevels specification.
+type levelSpec struct {
+	rules        []levelRule
+	root         Level
+	hasRootLevel bool
+}
+
+func parseLevels(spec string) (*levelSpec, error) {
+	levels := &levelSpec{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		i := strings.LastIndex(part, "=")
+		if i < 0 {
+			lvl, err := ParseLevel(part)
+			if err != nil {
+				return nil, err
+			}
+			levels.root, levels.hasRootLevel = lvl, true
+			continue
+		}
+		pattern := strings.TrimSpace(part[:i])
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid hlog level pattern %q: %w", pattern, err)
+		}
+		lvl, err := ParseLevel(strings.TrimSpace(part[i+1:]))
+		if err != nil {
+			return nil, err
+		}
+		levels.rules = append(levels.rules, levelRule{pattern: pattern, level: lvl})
+	}
+	return levels, nil
+}
+
+func (logger *Logger) applyLevels(levels *levelSpec) {
+	tree := logger.loadTree()
+	if tree == nil {
+		tree = logger.initTree()
+	}
+	if levels.hasRootLevel {
+		atomic.StoreUint32((*uint32)(&tree.root.Level), uint32(levels.root))
+	}
+	tree.mu.Lock()
+	tree.rules = levels.rules
+	tree.mu.Unlock()
+	tree.refresh()
+}
+
+func (logger *Logger) loadTree() *loggerTree {
+	if logger.root != nil {
+		return logger.tree
+	}
+	logger.lock().Lock()
+	defer logger.lock().Unlock()
+	return logger.tree
+}
+
+func (logger *Logger) initTree() *loggerTree {
+	root := logger
+	if logger.root != nil {
+		root = logger.root
+	}
+	root.lock().Lock()
+	defer root.lock().Unlock()
+	if root.tree == nil {
+		root.tree = &loggerTree{root: root, children: make(map[string]*Logger)}
+	}
+	return root.tree
+}
+
+// eachChild calls fn for every direct child of the logger.
+func (logger *Logger) eachChild(fn func(*Logger)) {
+	tree := logger.loadTree()
+	if tree == nil {
+		return
+	}
+	tree.mu.Lock()
+	var children []*Logger
+	for _, child := range tree.children {
+		if child.parent == logger {
+			children = append(children, child)
+		}
+	}
+	tree.mu.Unlock()
+	for _, child := range children {
+		fn(child)
+	}
+}
+
+// setRule gives the named logger name its own level.
+func (tree *loggerTree) setRule(name string, level Level) {
+	tree.mu.Lock()
+	tree.rules = append(tree.withoutRule(name), levelRule{pattern: name, level: level})
+	tree.mu.Unlock()
+	tree.refresh()
+}
+
+// removeRule removes the level given to the named logger name by setRule,
+// it follows the level of the matching patterns and ancestors again.
+func (tree *loggerTree) removeRule(name string) {
+	tree.mu.Lock()
+	tree.rules = tree.withoutRule(name)
+	tree.mu.Unlock()
+	tree.refresh()
+}
+
+// rule returns the level given to the named logger name itself, if any.
+func (tree *loggerTree) rule(name string) (Level, bool) {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	for i := len(tree.rules) - 1; i >= 0; i-- {
+		if tree.rules[i].pattern == name {
+			return tree.rules[i].level, true
+		}
+	}
+	return 0, false
+}
+
+// withoutRule returns a copy of the rules without the one of the named
+// logger name. It must be called with tree.mu held.
+func (tree *loggerTree) withoutRule(name string) []levelRule {
+	rules := make([]levelRule, 0, len(tree.rules)+1)
+	for _, rule := range tree.rules {
+		if rule.pattern != name {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// refresh recomputes the levels of all the named loggers.
+func (tree *loggerTree) refresh() {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	for name, child := range tree.children {
+		atomic.StoreUint32((*uint32)(&child.Level), uint32(tree.resolve(name)))
+	}
+}
+
+// resolve returns the level of the named logger name. It must be called with
+// tree.mu held.
+func (tree *loggerTree) resolve(name string) Level {
+	for candidate := name; candidate != ""; {
+		for i := len(tree.rules) - 1; i >= 0; i-- {
+			if ok, _ := path.Match(tree.rules[i].pattern, candidate); ok {
+				return tree.rules[i].level
+			}
+		}
+		if i := strings.LastIndex(candidate, "."); i >= 0 {
+			candidate = candidate[:i]
+		} else {
+			candidate = ""
+		}
+	}
+	return tree.root.level()
+}
+
+// names returns the sorted names of the named loggers.
+func (tree *loggerTree) names() []string {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	names := make([]string, 0, len(tree.children))
+	for name := range tree.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookup returns the named logger called name, or nil.
+func (tree *loggerTree) lookup(name string) *Logger {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	return tree.children[name]
+}
@@ -0,0 +1,79 @@
+package hlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamedLoggerField(t *testing.T) {
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.Named("db").Named("pool").WithField("logger", "user").Info("named")
+	}, func(fields Fields) {
+		assert.Equal(t, "db.pool", fields["logger"])
+		assert.Equal(t, "user", fields["fields.logger"])
+	})
+
+	LogAndAssertText(t, func(log *Logger) {
+		log.Named("http").Info("named")
+	}, func(fields map[string]string) {
+		assert.Equal(t, "http", fields["logger"])
+	})
+}
+
+func TestNamedLoggerIsCached(t *testing.T) {
+	logger := New()
+	assert.Same(t, logger.Named("db"), logger.Named("db"))
+	assert.Same(t, logger.Named("db").Named("pool"), logger.Named("db.pool"))
+	assert.Equal(t, "db.pool", logger.Named("db").Named("pool").Name())
+}
+
+func TestNamedLoggerInherits(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	db := logger.Named("db")
+	logger.SetOutput(&buffer)
+	logger.SetFormatter(new(JSONFormatter))
+
+	db.Info("hello")
+
+	var fields Fields
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &fields))
+	assert.Equal(t, "hello", fields["msg"])
+}
+
+func TestSetLevels(t *testing.T) {
+	logger := New()
+	db := logger.Named("db")
+	pool := db.Named("pool")
+	http := logger.Named("http")
+	other := logger.Named("other")
+
+	require.NoError(t, logger.SetLevels("db.*=debug, http=warn"))
+	assert.Equal(t, InfoLevel, db.GetLevel())
+	assert.Equal(t, DebugLevel, pool.GetLevel())
+	assert.Equal(t, DebugLevel, pool.Named("conn").GetLevel())
+	assert.Equal(t, WarnLevel, http.GetLevel())
+	assert.Equal(t, WarnLevel, http.Named("client").GetLevel())
+	assert.Equal(t, InfoLevel, other.GetLevel())
+
+	logger.SetLevel(ErrorLevel)
+	assert.Equal(t, ErrorLevel, db.GetLevel())
+	assert.Equal(t, ErrorLevel, other.GetLevel())
+	assert.Equal(t, WarnLevel, http.GetLevel())
+
+	db.SetLevel(TraceLevel)
+	assert.Equal(t, TraceLevel, db.GetLevel())
+	assert.Equal(t, DebugLevel, pool.GetLevel())
+	assert.Equal(t, ErrorLevel, logger.GetLevel())
+
+	require.NoError(t, logger.SetLevels("trace"))
+	assert.Equal(t, TraceLevel, logger.GetLevel())
+	assert.Equal(t, TraceLevel, http.GetLevel())
+
+	assert.Error(t, logger.SetLevels("db=loud"))
+	assert.Error(t, logger.SetLevels("[=debug"))
+}
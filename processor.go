@@ -0,0 +1,51 @@
+package hlog
+
+// Processor is called with each entry about to be logged, before the hooks
+// and the output see it, to enrich, rename, transform or drop it. The entry
+// owns its Data map and can be modified in place and returned. Returning
+// false vetoes the entry, unless it is logged at PanicLevel or FatalLevel.
+//
+// A processor can also return another entry, usually derived from the one
+// it got with WithField and the like. Unless its Message is set, such an
+// entry gets the Time, Level, Message and Caller of the original one.
+type Processor func(entry *Entry) (*Entry, bool)
+
+// AddProcessor appends a processor to the chain of the logger. Processors
+// run in the order they were added, after the context fields are added and
+// the lazy values resolved, and before redaction, so that no hook or output
+// sees what the redactor masks.
+func (logger *Logger) AddProcessor(processor Processor) {
+	logger.lock().Lock()
+	// copy so that entries being logged keep a consistent chain
+	processors := make([]Processor, len(logger.processors), len(logger.processors)+1)
+	copy(processors, logger.processors)
+	logger.processors = append(processors, processor)
+	logger.lock().Unlock()
+	logger.eachChild(func(child *Logger) {
+		child.AddProcessor(processor)
+	})
+}
+
+// process runs the processors on an entry owning its Data map. It returns
+// the entry to log, or nil when a processor vetoed it.
+func (entry *Entry) process(processors []Processor) *Entry {
+	for _, processor := range processors {
+		processed, ok := processor(entry)
+		if !ok || processed == nil {
+			if entry.Level <= FatalLevel {
+				continue
+			}
+			return nil
+		}
+		if processed != entry && processed.Message == "" {
+			if processed.Time.IsZero() {
+				processed.Time = entry.Time
+			}
+			processed.Level = entry.Level
+			processed.Message = entry.Message
+			processed.Caller = entry.Caller
+		}
+		entry = processed
+	}
+	return entry
+}
@@ -0,0 +1,63 @@
+package hlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessors(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &TextFormatter{DisableTimestamp: true}
+	hook := new(fieldsHook)
+	logger.AddHook(hook)
+
+	var order []string
+	logger.AddProcessor(func(entry *Entry) (*Entry, bool) {
+		order = append(order, "rename")
+		if v, ok := entry.Data["usr"]; ok {
+			delete(entry.Data, "usr")
+			entry.Data["user"] = v
+		}
+		return entry, true
+	})
+	logger.AddProcessor(func(entry *Entry) (*Entry, bool) {
+		order = append(order, "veto")
+		return entry, !strings.HasPrefix(entry.Message, "healthcheck")
+	})
+	logger.AddProcessor(func(entry *Entry) (*Entry, bool) {
+		order = append(order, "enrich")
+		return entry.WithField("service", "api"), true
+	})
+
+	logger.WithField("usr", "bob").Info("login")
+	assert.Equal(t, []string{"rename", "veto", "enrich"}, order)
+	assert.Equal(t, "level=info msg=login service=api user=bob\n", buffer.String())
+	assert.Equal(t, Fields{"service": "api", "user": "bob"}, hook.data)
+
+	order = nil
+	buffer.Reset()
+	logger.Info("healthcheck ok")
+	assert.Equal(t, []string{"rename", "veto"}, order)
+	assert.Empty(t, buffer.String())
+
+	child := logger.Named("child")
+	child.Warn("from child")
+	assert.Contains(t, buffer.String(), "service=api")
+}
+
+func TestProcessorCannotVetoPanic(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.AddProcessor(func(entry *Entry) (*Entry, bool) {
+		return nil, false
+	})
+
+	assert.Panics(t, func() { logger.Panic("boom") })
+	assert.Contains(t, buffer.String(), "boom")
+}
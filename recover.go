@@ -0,0 +1,125 @@
+package hlog
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Keys of the fields added by Recover and WithStack.
+const (
+	FieldKeyPanic = "panic"
+	FieldKeyStack = "stack"
+)
+
+// RecoverAction tells what Recover does once the panic is logged.
+type RecoverAction uint8
+
+const (
+	// RecoverRepanic panics again with the recovered value.
+	RecoverRepanic RecoverAction = iota
+	// RecoverExit exits through Logger.Exit.
+	RecoverExit
+	// RecoverSwallow returns normally, the panic is over.
+	RecoverSwallow
+)
+
+// RecoverOptions configures Recover.
+type RecoverOptions struct {
+	// Level the panic is logged at, PanicLevel by default. Logging at
+	// PanicLevel doesn't panic again, what happens next is up to Action.
+	Level Level
+	// Message of the entry, "recovered from panic" by default.
+	Message string
+	// Action is what to do once the panic is logged.
+	Action RecoverAction
+	// ExitCode is the code RecoverExit exits with, 1 by default.
+	ExitCode int
+}
+
+// Recover logs the panic of the goroutine with the standard logger, see
+// Logger.Recover.
+func Recover(opts RecoverOptions) {
+	if r := recover(); r != nil {
+		entry := std.newEntry()
+		defer std.releaseEntry(entry)
+		entry.recovered(r, opts)
+	}
+}
+
+// Go runs fn in a new goroutine, logging its panic with the standard
+// logger, see Logger.Go.
+func Go(fn func(), opts ...RecoverOptions) {
+	std.Go(fn, opts...)
+}
+
+// Recover logs the panic of the goroutine, if any. It must be called
+// directly with defer:
+//
+//	defer logger.Recover(hlog.RecoverOptions{Action: hlog.RecoverSwallow})
+//
+// See Entry.Recover.
+func (logger *Logger) Recover(opts RecoverOptions) {
+	if r := recover(); r != nil {
+		entry := logger.newEntry()
+		defer logger.releaseEntry(entry)
+		entry.recovered(r, opts)
+	}
+}
+
+// Recover logs the panic of the goroutine, if any, with the fields of the
+// entry. It must be called directly with defer:
+//
+//	defer entry.Recover(hlog.RecoverOptions{})
+//
+// The entry gets the recovered value in the `panic` field, as an error in
+// the `error` field for the hooks, and the stack of the goroutine in the
+// `stack` field. Then it panics again, exits or returns according to the
+// Action of the options.
+func (entry *Entry) Recover(opts RecoverOptions) {
+	if r := recover(); r != nil {
+		entry.recovered(r, opts)
+	}
+}
+
+func (entry *Entry) recovered(r interface{}, opts RecoverOptions) {
+	if opts.Message == "" {
+		opts.Message = "recovered from panic"
+	}
+	err, ok := r.(error)
+	if !ok {
+		err = errors.New(fmt.Sprint(r))
+	}
+
+	if entry.Logger.IsLevelEnabled(opts.Level) {
+		entry.WithFields(Fields{
+			FieldKeyPanic: fmt.Sprint(r),
+			ErrorKey:      err,
+			FieldKeyStack: captureStack(1),
+		}).emit(opts.Level, opts.Message)
+	}
+
+	switch opts.Action {
+	case RecoverExit:
+		code := opts.ExitCode
+		if code == 0 {
+			code = 1
+		}
+		entry.Logger.Exit(code)
+	case RecoverSwallow:
+	default:
+		panic(r)
+	}
+}
+
+// Go runs fn in a new goroutine, logging its panic with Recover. Without
+// options the panic is logged at PanicLevel and the goroutine panics again.
+func (logger *Logger) Go(fn func(), opts ...RecoverOptions) {
+	var o RecoverOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	go func() {
+		defer logger.Recover(o)
+		fn()
+	}()
+}
@@ -0,0 +1,105 @@
+package hlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRecoverLogger() (*Logger, *bytes.Buffer, *fieldsHook) {
+	var buffer bytes.Buffer
+	hook := new(fieldsHook)
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+	logger.AddHook(hook)
+	return logger, &buffer, hook
+}
+
+func TestRecoverSwallow(t *testing.T) {
+	logger, buffer, hook := newRecoverLogger()
+
+	func() {
+		defer logger.WithField("job", "sync").Recover(RecoverOptions{Level: ErrorLevel, Action: RecoverSwallow})
+		panic("boom")
+	}()
+
+	var fields Fields
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &fields))
+	assert.Equal(t, "error", fields["level"])
+	assert.Equal(t, "recovered from panic", fields["msg"])
+	assert.Equal(t, "boom", fields[FieldKeyPanic])
+	assert.Equal(t, "boom", fields[ErrorKey])
+	assert.Equal(t, "sync", fields["job"])
+	assert.Contains(t, fmt.Sprint(fields[FieldKeyStack]), "TestRecoverSwallow")
+	assert.EqualError(t, hook.data[ErrorKey].(error), "boom")
+}
+
+func TestRecoverRepanic(t *testing.T) {
+	logger, buffer, _ := newRecoverLogger()
+	err := errors.New("boom")
+
+	assert.PanicsWithValue(t, err, func() {
+		defer logger.Recover(RecoverOptions{})
+		panic(err)
+	})
+	var fields Fields
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &fields))
+	assert.Equal(t, "panic", fields["level"])
+}
+
+func TestRecoverExit(t *testing.T) {
+	logger, _, _ := newRecoverLogger()
+	var code int
+	logger.ExitFunc = func(c int) { code = c }
+
+	func() {
+		defer logger.Recover(RecoverOptions{Action: RecoverExit, ExitCode: 3})
+		panic("boom")
+	}()
+	assert.Equal(t, 3, code)
+}
+
+func TestRecoverNoPanic(t *testing.T) {
+	logger, buffer, _ := newRecoverLogger()
+	func() {
+		defer logger.Recover(RecoverOptions{})
+	}()
+	assert.Empty(t, buffer.String())
+}
+
+func TestGo(t *testing.T) {
+	logger, buffer, _ := newRecoverLogger()
+	done := make(chan struct{})
+	logger.Go(func() {
+		defer close(done)
+		panic("in goroutine")
+	}, RecoverOptions{Action: RecoverSwallow})
+	<-done
+
+	// the deferred close runs before Recover
+	assert.Eventually(t, func() bool {
+		logger.lock().Lock()
+		defer logger.lock().Unlock()
+		return bytes.Contains(buffer.Bytes(), []byte("in goroutine"))
+	}, time.Second, time.Millisecond)
+}
+
+func TestRecoverStandardLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	out := std.Out
+	defer std.SetOutput(out)
+	std.SetOutput(&buffer)
+
+	func() {
+		defer Recover(RecoverOptions{Action: RecoverSwallow})
+		panic("std boom")
+	}()
+	assert.Contains(t, buffer.String(), "std boom")
+}
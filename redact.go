@@ -0,0 +1,405 @@
+package hlog
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RedactAction tells how a Redactor masks what a rule matched.
+type RedactAction uint8
+
+const (
+	// RedactFull replaces the value with the mask of the Redactor.
+	RedactFull RedactAction = iota
+	// RedactPartial only leaves the last characters, e.g. "****1234".
+	RedactPartial
+	// RedactHash replaces the value with a hash of it, so that entries
+	// about the same value can still be correlated.
+	RedactHash
+	// RedactDrop removes the field. Matches in the message are replaced
+	// with the mask, like RedactFull does.
+	RedactDrop
+)
+
+// RedactRule tells what a Redactor masks and how.
+type RedactRule struct {
+	// Keys are the field keys the rule applies to, whatever their value,
+	// and the keys of the maps and structs nested in field values. They
+	// are case insensitive path.Match patterns, e.g. "password" or
+	// "*_token".
+	Keys []string
+	// Pattern is searched in the message and in the string, number, error
+	// and fmt.Stringer values of all the fields, including those nested in
+	// maps, slices and structs. Only the matches are masked.
+	Pattern *regexp.Regexp
+	// Action is how the matched value is masked.
+	Action RedactAction
+	// Keep is the number of characters left by RedactPartial, 4 by default.
+	Keep int
+}
+
+// Patterns of the DefaultRedactRules detectors.
+var (
+	EmailPattern      = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	CardNumberPattern = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	BearerPattern     = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`)
+)
+
+// DefaultRedactRules returns rules masking the usual secrets: passwords,
+// tokens, keys and authorization headers by key, bearer tokens, card
+// numbers and emails wherever they appear.
+func DefaultRedactRules() []RedactRule {
+	return []RedactRule{
+		{Keys: []string{"password", "passwd", "*_password", "secret", "*_secret", "token", "*_token", "api_key", "apikey", "authorization", "cookie"}},
+		{Pattern: BearerPattern},
+		{Pattern: CardNumberPattern, Action: RedactPartial},
+		{Pattern: EmailPattern, Action: RedactHash},
+	}
+}
+
+// RedactorOptions configures a Redactor.
+type RedactorOptions struct {
+	// Rules are applied in order, the first rule matching a key wins over
+	// the patterns.
+	Rules []RedactRule
+	// Mask replaces what RedactFull masks, "[REDACTED]" by default.
+	Mask string
+	// HashKey makes RedactHash an HMAC-SHA256 with that key instead of a
+	// plain SHA-256. Low entropy values like card numbers can be recovered
+	// from their plain hash.
+	HashKey []byte
+}
+
+// Redactor masks sensitive field values and parts of the message. A logger
+// with a Redactor applies it to each entry before the hooks and the
+// formatter see it, see Logger.SetRedactor.
+//
+// A Redactor is safe for concurrent use and can be shared between loggers.
+type Redactor struct {
+	opts     RedactorOptions
+	keyRules []RedactRule
+	patterns []RedactRule
+}
+
+// NewRedactor returns a Redactor with the given options. It panics if a key
+// of a rule is a malformed pattern.
+func NewRedactor(opts RedactorOptions) *Redactor {
+	if opts.Mask == "" {
+		opts.Mask = "[REDACTED]"
+	}
+	r := &Redactor{opts: opts}
+	for _, rule := range opts.Rules {
+		if rule.Keep <= 0 {
+			rule.Keep = 4
+		}
+		if len(rule.Keys) > 0 {
+			keys := make([]string, len(rule.Keys))
+			for i, k := range rule.Keys {
+				keys[i] = strings.ToLower(k)
+				if _, err := path.Match(keys[i], ""); err != nil {
+					panic(fmt.Sprintf("hlog: invalid redact key %q, %v", k, err))
+				}
+			}
+			rule.Keys = keys
+			r.keyRules = append(r.keyRules, rule)
+		}
+		if rule.Pattern != nil {
+			r.patterns = append(r.patterns, rule)
+		}
+	}
+	return r
+}
+
+// keyRule returns the first rule matching the key, or nil.
+func (r *Redactor) keyRule(key string) *RedactRule {
+	if len(r.keyRules) == 0 {
+		return nil
+	}
+	key = strings.ToLower(key)
+	for i := range r.keyRules {
+		for _, pattern := range r.keyRules[i].Keys {
+			if ok, _ := path.Match(pattern, key); ok {
+				return &r.keyRules[i]
+			}
+		}
+	}
+	return nil
+}
+
+// mask masks a whole value according to the rule.
+func (r *Redactor) mask(rule *RedactRule, s string) string {
+	switch rule.Action {
+	case RedactPartial:
+		if len(s) <= 2*rule.Keep {
+			return r.opts.Mask
+		}
+		return "****" + s[len(s)-rule.Keep:]
+	case RedactHash:
+		var h hash.Hash
+		if len(r.opts.HashKey) > 0 {
+			h = hmac.New(sha256.New, r.opts.HashKey)
+		} else {
+			h = sha256.New()
+		}
+		h.Write([]byte(s))
+		return "sha256:" + hex.EncodeToString(h.Sum(nil))[:16]
+	default:
+		return r.opts.Mask
+	}
+}
+
+// redactString masks the pattern matches found in s. It reports whether
+// anything was masked, and whether the string has to be dropped.
+func (r *Redactor) redactString(s string) (string, bool, bool) {
+	masked := false
+	for i := range r.patterns {
+		rule := &r.patterns[i]
+		if !rule.Pattern.MatchString(s) {
+			continue
+		}
+		if rule.Action == RedactDrop {
+			return "", true, true
+		}
+		s = rule.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+			return r.mask(rule, match)
+		})
+		masked = true
+	}
+	return s, masked, false
+}
+
+// redactMessage masks the pattern matches found in the message.
+func (r *Redactor) redactMessage(s string) string {
+	for i := range r.patterns {
+		rule := &r.patterns[i]
+		if rule.Action == RedactDrop {
+			s = rule.Pattern.ReplaceAllLiteralString(s, r.opts.Mask)
+			continue
+		}
+		s = rule.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+			return r.mask(rule, match)
+		})
+	}
+	return s
+}
+
+// maxRedactDepth bounds the recursion of redactValue into nested values,
+// which may be cyclic through pointers.
+const maxRedactDepth = 8
+
+// redactValue masks a field value. The patterns are searched in strings,
+// the decimal text of numbers, and the text of errors and fmt.Stringers.
+// Maps, slices, arrays and structs are searched recursively, the key rules
+// applying to map keys and to the JSON names of struct fields. A value in
+// which something was masked is replaced with a copy: maps become
+// map[string]interface{}, slices and arrays []interface{}, structs their
+// JSON form as a map. It reports whether anything was masked, and whether
+// the field has to be dropped.
+func (r *Redactor) redactValue(key string, v interface{}) (interface{}, bool, bool) {
+	return r.redactKeyed(key, v, 0)
+}
+
+// redactKeyed masks a value stored under a key, a field key or a nested map
+// key.
+func (r *Redactor) redactKeyed(key string, v interface{}, depth int) (interface{}, bool, bool) {
+	if rule := r.keyRule(key); rule != nil {
+		if rule.Action == RedactDrop {
+			return nil, true, true
+		}
+		return r.mask(rule, fmt.Sprint(v)), true, false
+	}
+	return r.redactNested(v, depth)
+}
+
+// redactNested masks a value without looking at its key, see redactValue.
+func (r *Redactor) redactNested(v interface{}, depth int) (interface{}, bool, bool) {
+	switch v := v.(type) {
+	case nil:
+		return nil, false, false
+	case string:
+		return r.redactString(v)
+	case []string:
+		var redacted []string
+		for i, s := range v {
+			masked, ok, drop := r.redactString(s)
+			if drop {
+				return nil, true, true
+			}
+			if ok {
+				if redacted == nil {
+					redacted = append([]string(nil), v...)
+				}
+				redacted[i] = masked
+			}
+		}
+		if redacted != nil {
+			return redacted, true, false
+		}
+		return v, false, false
+	case error, fmt.Stringer:
+		// Errors often carry the values they failed on. A masked value is
+		// replaced with its masked text, fmt recovers from a nil receiver.
+		if s, ok, drop := r.redactString(fmt.Sprint(v)); ok {
+			return s, true, drop
+		}
+		return v, false, false
+	case json.Number:
+		if s, ok, drop := r.redactString(string(v)); ok {
+			return s, true, drop
+		}
+		return v, false, false
+	}
+	if depth >= maxRedactDepth {
+		return v, false, false
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		// card numbers are often logged as integers
+		if s, ok, drop := r.redactString(fmt.Sprint(v)); ok {
+			return s, true, drop
+		}
+	case reflect.Float32, reflect.Float64:
+		// formatted without exponent, as a float64 holds card numbers exactly
+		if s, ok, drop := r.redactString(strconv.FormatFloat(rv.Float(), 'f', -1, 64)); ok {
+			return s, true, drop
+		}
+	case reflect.Ptr, reflect.Interface:
+		if !rv.IsNil() {
+			if redacted, ok, drop := r.redactNested(rv.Elem().Interface(), depth+1); ok {
+				return redacted, true, drop
+			}
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		var redacted map[string]interface{}
+		iter := rv.MapRange()
+		for iter.Next() {
+			k := iter.Key().String()
+			value, ok, drop := r.redactKeyed(k, iter.Value().Interface(), depth+1)
+			if !ok {
+				continue
+			}
+			if redacted == nil {
+				redacted = make(map[string]interface{}, rv.Len())
+				for it := rv.MapRange(); it.Next(); {
+					redacted[it.Key().String()] = it.Value().Interface()
+				}
+			}
+			if drop {
+				delete(redacted, k)
+			} else {
+				redacted[k] = value
+			}
+		}
+		if redacted != nil {
+			return redacted, true, false
+		}
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			// raw bytes
+			break
+		}
+		var redacted []interface{}
+		for i := 0; i < rv.Len(); i++ {
+			value, ok, drop := r.redactNested(rv.Index(i).Interface(), depth+1)
+			if drop {
+				return nil, true, true
+			}
+			if !ok {
+				continue
+			}
+			if redacted == nil {
+				redacted = make([]interface{}, rv.Len())
+				for j := range redacted {
+					redacted[j] = rv.Index(j).Interface()
+				}
+			}
+			redacted[i] = value
+		}
+		if redacted != nil {
+			return redacted, true, false
+		}
+	case reflect.Struct:
+		// The fields are searched in the JSON form of the struct, the one
+		// the JSONFormatter writes.
+		data, err := json.Marshal(v)
+		if err != nil {
+			break
+		}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var generic interface{}
+		if decoder.Decode(&generic) != nil {
+			break
+		}
+		if redacted, ok, drop := r.redactNested(generic, depth); ok {
+			return redacted, true, drop
+		}
+	}
+	return v, false, false
+}
+
+// redact applies the redactor to an entry being logged. It must only be
+// called on entries owning their Data map.
+func (entry *Entry) redact(r *Redactor) {
+	entry.Message = r.redactMessage(entry.Message)
+	for k, v := range entry.Data {
+		redacted, masked, drop := r.redactValue(k, v)
+		if drop {
+			delete(entry.Data, k)
+		} else if masked {
+			entry.Data[k] = redacted
+		}
+	}
+
+	// The typed fields are shared with the entry the logged one was created
+	// from, they are only copied when something is masked.
+	var fields []Field
+	for i := range entry.fields {
+		f := entry.fields[i]
+		redacted, masked, drop := r.redactValue(f.key, f.Value())
+		if !masked {
+			if fields != nil {
+				fields = append(fields, f)
+			}
+			continue
+		}
+		if fields == nil {
+			fields = make([]Field, i, len(entry.fields))
+			copy(fields, entry.fields[:i])
+		}
+		if drop {
+			// Don't let a shadowed boxed field show up.
+			delete(entry.Data, f.key)
+			continue
+		}
+		fields = append(fields, Any(f.key, redacted))
+	}
+	if fields != nil {
+		entry.fields = fields
+	}
+}
+
+// SetRedactor sets the redactor of the logger, nil disables redaction.
+func (logger *Logger) SetRedactor(redactor *Redactor) {
+	logger.lock().Lock()
+	logger.redactor = redactor
+	logger.lock().Unlock()
+	logger.eachChild(func(child *Logger) {
+		child.SetRedactor(redactor)
+	})
+}
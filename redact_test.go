@@ -0,0 +1,185 @@
+package hlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+	hook := new(fieldsHook)
+	logger.AddHook(hook)
+	logger.SetRedactor(NewRedactor(RedactorOptions{Rules: DefaultRedactRules()}))
+
+	logger.WithFields(Fields{
+		"Password":     "hunter2",
+		"github_token": "ghp_abc",
+		"card":         "paid with 4111 1111 1111 1111",
+		"user":         "bob",
+		"count":        3,
+	}).With(Str("header", "Bearer abc.def"), Int("attempt", 2)).Info("mail sent to bob@example.com")
+
+	var fields Fields
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &fields))
+	assert.Equal(t, "[REDACTED]", fields["Password"])
+	assert.Equal(t, "[REDACTED]", fields["github_token"])
+	assert.Equal(t, "paid with ****1111", fields["card"])
+	assert.Equal(t, "bob", fields["user"])
+	assert.Equal(t, 3.0, fields["count"])
+	assert.Equal(t, "[REDACTED]", fields["header"])
+	assert.Equal(t, 2.0, fields["attempt"])
+	assert.Regexp(t, `^mail sent to sha256:[0-9a-f]{16}$`, fields["msg"])
+	assert.NotContains(t, buffer.String(), "bob@example.com")
+
+	assert.Equal(t, "[REDACTED]", hook.data["Password"])
+	assert.Equal(t, "[REDACTED]", hook.data["header"])
+}
+
+func TestRedactActions(t *testing.T) {
+	secret := regexp.MustCompile(`s3cr3t`)
+	r := NewRedactor(RedactorOptions{
+		Rules: []RedactRule{
+			{Keys: []string{"ssn"}, Action: RedactPartial, Keep: 2},
+			{Keys: []string{"session_*"}, Action: RedactDrop},
+			{Keys: []string{"email"}, Action: RedactHash},
+			{Pattern: secret, Action: RedactDrop},
+		},
+		Mask:    "***",
+		HashKey: []byte("key"),
+	})
+	entry := NewEntry(New()).WithFields(Fields{
+		"ssn":        "123-45-6789",
+		"session_id": "abc",
+		"email":      "bob@example.com",
+		"note":       "the s3cr3t is out",
+		"other":      "fine",
+	}).With(Strs("tags", []string{"a", "s3cr3t"}), Str("session_key", "k"))
+	entry.Data["session_key"] = "shadowed"
+	entry.Message = "the s3cr3t again"
+
+	entry.redact(r)
+	assert.Equal(t, "****89", entry.Data["ssn"])
+	assert.NotContains(t, entry.Data, "session_id")
+	assert.NotContains(t, entry.Data, "session_key")
+	assert.Regexp(t, `^sha256:[0-9a-f]{16}$`, entry.Data["email"])
+	assert.NotContains(t, entry.Data, "note")
+	assert.Equal(t, "fine", entry.Data["other"])
+	assert.Empty(t, entry.fields)
+	assert.Equal(t, "the *** again", entry.Message)
+}
+
+func TestRedactSharedFields(t *testing.T) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.SetRedactor(NewRedactor(RedactorOptions{Rules: DefaultRedactRules()}))
+
+	entry := logger.With(Str("token", "abc"))
+	entry.Info("first")
+	assert.Equal(t, "abc", entry.Fields()[0].Value())
+}
+
+type redactStringer string
+
+func (s redactStringer) String() string {
+	return string(s)
+}
+
+func TestRedactErrors(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+	hook := new(fieldsHook)
+	logger.AddHook(hook)
+	logger.SetRedactor(NewRedactor(RedactorOptions{Rules: DefaultRedactRules()}))
+
+	logger.WithError(errors.New("login failed for bob@example.com")).
+		WithField("e", errors.New("carol@example.com")).
+		WithField("who", redactStringer("dave@example.com")).
+		WithField("plain", errors.New("nothing to hide")).
+		With(NamedErr("card", errors.New("card 4111 1111 1111 1111"))).
+		Error("failed")
+
+	var fields Fields
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &fields))
+	assert.Regexp(t, `^login failed for sha256:[0-9a-f]{16}$`, fields[ErrorKey])
+	assert.Regexp(t, `^sha256:[0-9a-f]{16}$`, fields["e"])
+	assert.Regexp(t, `^sha256:[0-9a-f]{16}$`, fields["who"])
+	assert.Equal(t, "card ****1111", fields["card"])
+	assert.Equal(t, "nothing to hide", fields["plain"])
+	for _, secret := range []string{"bob@example.com", "carol@example.com", "dave@example.com", "4111 1111"} {
+		assert.NotContains(t, buffer.String(), secret)
+	}
+
+	assert.Regexp(t, `^login failed for sha256:`, hook.data[ErrorKey])
+	assert.Equal(t, "card ****1111", hook.data["card"])
+}
+
+type redactRequest struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+func TestRedactNested(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+	logger.SetRedactor(NewRedactor(RedactorOptions{Rules: DefaultRedactRules()}))
+
+	req := Fields{"password": "hunter2", "user": "bob", "headers": map[string]string{"Authorization": "Basic abc"}}
+	list := []interface{}{"fine", map[string]interface{}{"api_key": "k"}}
+	logger.WithField("req", req).
+		WithField("list", list).
+		WithField("body", &redactRequest{User: "bob", Password: "hunter2"}).
+		WithField("plain", map[string]int{"n": 1}).
+		Info("nested")
+
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &fields))
+	assert.Equal(t, map[string]interface{}{
+		"password": "[REDACTED]",
+		"user":     "bob",
+		"headers":  map[string]interface{}{"Authorization": "[REDACTED]"},
+	}, fields["req"])
+	assert.Equal(t, []interface{}{"fine", map[string]interface{}{"api_key": "[REDACTED]"}}, fields["list"])
+	assert.Equal(t, map[string]interface{}{"user": "bob", "password": "[REDACTED]"}, fields["body"])
+	assert.Equal(t, map[string]interface{}{"n": 1.0}, fields["plain"])
+	assert.NotContains(t, buffer.String(), "hunter2")
+
+	// the logged maps are left untouched
+	assert.Equal(t, "hunter2", req["password"])
+	assert.Equal(t, "k", list[1].(map[string]interface{})["api_key"])
+}
+
+func TestRedactNumbers(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+	logger.SetRedactor(NewRedactor(RedactorOptions{Rules: DefaultRedactRules()}))
+
+	logger.WithField("pan", 4111111111111111).
+		WithField("float", 4111111111111111.0).
+		WithField("nested", Fields{"pan": uint64(4111111111111111)}).
+		With(Int64("typed", 4111111111111111), Int("count", 42)).
+		Info("numbers")
+
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &fields))
+	assert.Equal(t, "****1111", fields["pan"])
+	assert.Equal(t, "****1111", fields["float"])
+	assert.Equal(t, map[string]interface{}{"pan": "****1111"}, fields["nested"])
+	assert.Equal(t, "****1111", fields["typed"])
+	assert.Equal(t, 42.0, fields["count"])
+	assert.NotContains(t, buffer.String(), "4111111111111111")
+}
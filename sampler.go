@@ -0,0 +1,115 @@
+package hlog
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// samplerCounters is the number of counters kept per level. Messages are
+// hashed onto them, so memory stays bounded however many distinct messages
+// are logged, at the cost of rare collisions.
+const samplerCounters = 4096
+
+// numLevels is the number of log levels, for arrays indexed by Level.
+const numLevels = int(TraceLevel) + 1
+
+// Sampler caps the number of entries logged for a same level and message.
+// In every interval the first entries are logged, after which only one in
+// thereafter is. Entries at PanicLevel and FatalLevel are never sampled.
+//
+// A Sampler is safe for concurrent use and can be shared between loggers.
+type Sampler struct {
+	tick       time.Duration
+	first      uint64
+	thereafter uint64
+
+	counters [numLevels][samplerCounters]samplerCounter
+	dropped  [numLevels]uint64
+}
+
+type samplerCounter struct {
+	resetAt int64
+	count   uint64
+}
+
+// NewSampler returns a Sampler letting through the first entries with the
+// same level and message in each tick, then every thereafter-th one. A
+// thereafter of zero drops all the entries past the first ones.
+func NewSampler(tick time.Duration, first, thereafter int) *Sampler {
+	return &Sampler{
+		tick:       tick,
+		first:      uint64(first),
+		thereafter: uint64(thereafter),
+	}
+}
+
+// Dropped returns the number of entries dropped at the given level.
+func (s *Sampler) Dropped(level Level) uint64 {
+	if int(level) >= numLevels {
+		return 0
+	}
+	return atomic.LoadUint64(&s.dropped[level])
+}
+
+// DroppedTotal returns the number of entries dropped at all levels.
+func (s *Sampler) DroppedTotal() uint64 {
+	var total uint64
+	for i := range s.dropped {
+		total += atomic.LoadUint64(&s.dropped[i])
+	}
+	return total
+}
+
+// allow reports whether an entry of the named logger should be logged, and
+// counts it as dropped otherwise.
+func (s *Sampler) allow(level Level, name, msg string, t time.Time) bool {
+	if level <= FatalLevel || int(level) >= numLevels {
+		return true
+	}
+	h := fnv32a(fnv32a(fnvOffset32, name), msg)
+	n := s.counters[level][h%samplerCounters].inc(t, s.tick)
+	if n <= s.first || (s.thereafter > 0 && (n-s.first)%s.thereafter == 0) {
+		return true
+	}
+	atomic.AddUint64(&s.dropped[level], 1)
+	return false
+}
+
+// inc counts an entry logged at t and returns the count in the current tick.
+func (c *samplerCounter) inc(t time.Time, tick time.Duration) uint64 {
+	now := t.UnixNano()
+	resetAt := atomic.LoadInt64(&c.resetAt)
+	if resetAt > now {
+		return atomic.AddUint64(&c.count, 1)
+	}
+	atomic.StoreUint64(&c.count, 1)
+	if !atomic.CompareAndSwapInt64(&c.resetAt, resetAt, now+tick.Nanoseconds()) {
+		// another goroutine started the new tick
+		return atomic.AddUint64(&c.count, 1)
+	}
+	return 1
+}
+
+const (
+	fnvOffset32 = 2166136261
+	fnvPrime32  = 16777619
+)
+
+// fnv32a continues a FNV-1a hash with s, without allocating like hash/fnv.
+func fnv32a(h uint32, s string) uint32 {
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= fnvPrime32
+	}
+	return h
+}
+
+// SetSampler sets the sampler of the logger, nil disables sampling.
+func (logger *Logger) SetSampler(sampler *Sampler) {
+	logger.lock().Lock()
+	logger.sampler = sampler
+	logger.lock().Unlock()
+	logger.eachChild(func(child *Logger) {
+		child.SetSampler(sampler)
+	})
+}
@@ -0,0 +1,57 @@
+package hlog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampler(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &TextFormatter{DisableTimestamp: true}
+	sampler := NewSampler(time.Hour, 2, 3)
+	logger.SetSampler(sampler)
+
+	for i := 0; i < 10; i++ {
+		logger.Info("hot")
+	}
+	logger.Warn("hot")
+	logger.Info("cold")
+
+	// first 2, then the 5th and 8th
+	assert.Equal(t, 4, strings.Count(buffer.String(), "level=info msg=hot"))
+	assert.Equal(t, 1, strings.Count(buffer.String(), "level=warning msg=hot"))
+	assert.Equal(t, 1, strings.Count(buffer.String(), "msg=cold"))
+	assert.Equal(t, uint64(6), sampler.Dropped(InfoLevel))
+	assert.Equal(t, uint64(0), sampler.Dropped(WarnLevel))
+	assert.Equal(t, uint64(6), sampler.DroppedTotal())
+}
+
+func TestSamplerTick(t *testing.T) {
+	sampler := NewSampler(time.Second, 1, 0)
+	now := time.Now()
+
+	assert.True(t, sampler.allow(InfoLevel, "", "msg", now))
+	assert.False(t, sampler.allow(InfoLevel, "", "msg", now))
+	assert.True(t, sampler.allow(InfoLevel, "", "msg", now.Add(2*time.Second)))
+	assert.True(t, sampler.allow(FatalLevel, "", "msg", now))
+	assert.True(t, sampler.allow(FatalLevel, "", "msg", now))
+}
+
+func TestSamplerSkipsHooks(t *testing.T) {
+	hook := new(fieldsHook)
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.AddHook(hook)
+	logger.SetSampler(NewSampler(time.Hour, 1, 0))
+
+	logger.WithField("n", 1).Info("hot")
+	logger.WithField("n", 2).Info("hot")
+
+	assert.Equal(t, 1, hook.data["n"])
+}
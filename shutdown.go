@@ -0,0 +1,149 @@
+package hlog
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Flusher is implemented by hooks and outputs that buffer entries, for
+// Logger.Shutdown to wait until they are sent.
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// Closer is implemented by hooks and outputs that hold resources, for
+// Logger.Shutdown to release them.
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
+// ShutdownTimeout bounds how long Exit, and so Fatal, wait for the logger
+// to shut down.
+var ShutdownTimeout = 5 * time.Second
+
+// Shutdown shuts down the standard logger, see Logger.Shutdown.
+func Shutdown(ctx context.Context) error {
+	return std.Shutdown(ctx)
+}
+
+// Shutdown drains everything the logger buffers before the process exits,
+// unless ctx is done first. Pending deduplication summaries are logged, then
+// the hooks and the output are flushed and closed when they implement
+// Flusher and Closer. Hooks with a Flush method without arguments, like the
+// asynchronous Graylog and Sentry hooks, are flushed too. Outputs are never
+// closed through io.Closer, so that os.Stderr stays open.
+//
+// The errors are returned as HookErrors, the ones of the output having no
+// Hook. The logger can still be used afterwards, closed hooks and outputs
+// usually fall back to synchronous delivery.
+func (logger *Logger) Shutdown(ctx context.Context) error {
+	logger.lock().Lock()
+	deduper := logger.deduper
+	hooks := logger.Hooks.unique()
+	out := logger.Out
+	logger.lock().Unlock()
+
+	if deduper != nil {
+		deduper.Flush()
+	}
+
+	var errs HookErrors
+	for _, hook := range hooks {
+		if err := flushTarget(ctx, hook); err != nil {
+			errs = append(errs, &HookError{Hook: hook, Err: err})
+		}
+		if err := closeTarget(ctx, hook); err != nil {
+			errs = append(errs, &HookError{Hook: hook, Err: err})
+		}
+	}
+
+	var err error
+	if f, ok := out.(interface{ Flush() error }); ok {
+		// bufio.Writer and the like aren't safe for concurrent use, the
+		// logger lock serializes the flush with the writes.
+		logger.lock().Lock()
+		err = f.Flush()
+		logger.lock().Unlock()
+	} else {
+		err = flushTarget(ctx, out)
+	}
+	if err != nil {
+		errs = append(errs, &HookError{Err: fmt.Errorf("failed to flush log output, %w", err)})
+	}
+	if err := closeTarget(ctx, out); err != nil {
+		errs = append(errs, &HookError{Err: fmt.Errorf("failed to close log output, %w", err)})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// shutdownForExit shuts down the logger within ShutdownTimeout.
+func (logger *Logger) shutdownForExit() {
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	if err := logger.Shutdown(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to shut down logger, %v\n", err)
+	}
+}
+
+// unique returns the hooks of all the levels, each once.
+func (hooks LevelHooks) unique() []Hook {
+	var unique []Hook
+	seen := make(map[Hook]bool)
+	for _, level := range AllLevels {
+		for _, hook := range hooks[level] {
+			if reflect.TypeOf(hook).Comparable() {
+				if seen[hook] {
+					continue
+				}
+				seen[hook] = true
+			}
+			unique = append(unique, hook)
+		}
+	}
+	return unique
+}
+
+// flushTarget flushes a hook or an output.
+func flushTarget(ctx context.Context, v interface{}) error {
+	switch f := v.(type) {
+	case Flusher:
+		return f.Flush(ctx)
+	case interface{ Flush() error }:
+		return waitContext(ctx, f.Flush)
+	case interface{ Flush() }:
+		return waitContext(ctx, func() error {
+			f.Flush()
+			return nil
+		})
+	}
+	return nil
+}
+
+// closeTarget closes a hook or an output implementing Closer.
+func closeTarget(ctx context.Context, v interface{}) error {
+	if c, ok := v.(Closer); ok {
+		return c.Close(ctx)
+	}
+	return nil
+}
+
+// waitContext runs fn, returning early if ctx is done first.
+func waitContext(ctx context.Context, fn func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,119 @@
+package hlog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lifecycleHook records the Flush and Close calls it gets.
+type lifecycleHook struct {
+	calls []string
+	err   error
+}
+
+func (h *lifecycleHook) Levels() []Level {
+	return []Level{ErrorLevel, InfoLevel}
+}
+
+func (h *lifecycleHook) Fire(*Entry) error {
+	return nil
+}
+
+func (h *lifecycleHook) Flush(context.Context) error {
+	h.calls = append(h.calls, "flush")
+	return h.err
+}
+
+func (h *lifecycleHook) Close(context.Context) error {
+	h.calls = append(h.calls, "close")
+	return nil
+}
+
+// legacyFlushHook has a Flush method without arguments.
+type legacyFlushHook struct {
+	flushed bool
+}
+
+func (h *legacyFlushHook) Levels() []Level {
+	return AllLevels
+}
+
+func (h *legacyFlushHook) Fire(*Entry) error {
+	return nil
+}
+
+func (h *legacyFlushHook) Flush() {
+	h.flushed = true
+}
+
+func TestShutdown(t *testing.T) {
+	var buffer bytes.Buffer
+	out := bufio.NewWriter(&buffer)
+	logger := New()
+	logger.Out = out
+	logger.Formatter = &TextFormatter{DisableTimestamp: true}
+	hook := &lifecycleHook{}
+	legacy := &legacyFlushHook{}
+	logger.AddHook(hook)
+	logger.AddHook(legacy)
+	logger.SetDeduper(NewDeduper(DedupOptions{}))
+
+	logger.Info("buffered")
+	logger.Info("buffered")
+	assert.Empty(t, buffer.String())
+
+	require.NoError(t, logger.Shutdown(context.Background()))
+	assert.Equal(t, []string{"flush", "close"}, hook.calls)
+	assert.True(t, legacy.flushed)
+	assert.Contains(t, buffer.String(), "msg=buffered")
+	assert.Contains(t, buffer.String(), "repeated=1")
+}
+
+func TestShutdownErrors(t *testing.T) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.AddHook(&lifecycleHook{err: errors.New("unreachable")})
+
+	err := logger.Shutdown(context.Background())
+	assert.EqualError(t, err, "*hlog.lifecycleHook: unreachable")
+	var errs HookErrors
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0].Err, "unreachable")
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	gate := make(chan struct{})
+	defer close(gate)
+	logger := New()
+	logger.Out = &gatedWriter{gate: gate}
+	logger.SetOutput(NewAsyncWriter(logger.Out, AsyncWriterOptions{}))
+	logger.Info("stuck")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := logger.Shutdown(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+}
+
+func TestFatalShutsDown(t *testing.T) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	hook := &lifecycleHook{}
+	logger.AddHook(hook)
+	var code int
+	logger.ExitFunc = func(c int) {
+		code = c
+		assert.Equal(t, []string{"flush", "close"}, hook.calls)
+	}
+
+	logger.Fatal("bye")
+	assert.Equal(t, 1, code)
+}
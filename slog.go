@@ -0,0 +1,246 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// Levels of slog records mapped to the hlog levels slog lacks. Records below
+// slog.LevelDebug are logged at TraceLevel.
+const (
+	SlogLevelTrace = slog.Level(-8)
+	SlogLevelFatal = slog.Level(12)
+	SlogLevelPanic = slog.Level(16)
+)
+
+// FromSlogLevel returns the hlog level of an slog level.
+func FromSlogLevel(level slog.Level) Level {
+	switch {
+	case level >= SlogLevelPanic:
+		return PanicLevel
+	case level >= SlogLevelFatal:
+		return FatalLevel
+	case level >= slog.LevelError:
+		return ErrorLevel
+	case level >= slog.LevelWarn:
+		return WarnLevel
+	case level >= slog.LevelInfo:
+		return InfoLevel
+	case level >= slog.LevelDebug:
+		return DebugLevel
+	default:
+		return TraceLevel
+	}
+}
+
+// SlogLevel returns the slog level of an hlog level.
+func SlogLevel(level Level) slog.Level {
+	switch level {
+	case PanicLevel:
+		return SlogLevelPanic
+	case FatalLevel:
+		return SlogLevelFatal
+	case ErrorLevel:
+		return slog.LevelError
+	case WarnLevel:
+		return slog.LevelWarn
+	case InfoLevel:
+		return slog.LevelInfo
+	case DebugLevel:
+		return slog.LevelDebug
+	default:
+		return SlogLevelTrace
+	}
+}
+
+// SlogHandler is an slog.Handler logging the records with a Logger, through
+// its processors, hooks and output:
+//
+//	slog.SetDefault(slog.New(hlog.NewSlogHandler(logger)))
+//
+// The attributes become typed fields, the ones of groups being prefixed
+// with the group names joined by dots, e.g. `req.method`. Records at
+// SlogLevelFatal and SlogLevelPanic are logged at FatalLevel and PanicLevel,
+// without exiting nor panicking. When the logger reports the caller, it is
+// the one recorded by slog.
+type SlogHandler struct {
+	logger *Logger
+	fields []Field
+	prefix string
+}
+
+// NewSlogHandler returns an slog.Handler logging with the logger.
+func NewSlogHandler(logger *Logger) *SlogHandler {
+	return &SlogHandler{logger: logger}
+}
+
+// Enabled reports whether the logger logs records at that level.
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.IsLevelEnabled(FromSlogLevel(level))
+}
+
+// Handle logs the record.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make([]Field, len(h.fields), len(h.fields)+r.NumAttrs())
+	copy(fields, h.fields)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendSlogAttr(fields, h.prefix, a)
+		return true
+	})
+
+	entry := NewEntry(h.logger).With(fields...)
+	entry.Time = r.Time
+	entry.Context = ctx
+	if r.PC != 0 {
+		entry.caller = callerAt(r.PC)
+	}
+	entry.emit(FromSlogLevel(r.Level), r.Message)
+	return nil
+}
+
+// WithAttrs returns a handler adding the attributes to every record.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	fields := make([]Field, len(h.fields), len(h.fields)+len(attrs))
+	copy(fields, h.fields)
+	for _, a := range attrs {
+		fields = appendSlogAttr(fields, h.prefix, a)
+	}
+	return &SlogHandler{logger: h.logger, fields: fields, prefix: h.prefix}
+}
+
+// WithGroup returns a handler prefixing the keys of the attributes added
+// later with the group name.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &SlogHandler{logger: h.logger, fields: h.fields, prefix: h.prefix + name + "."}
+}
+
+// appendSlogAttr appends the fields of an attribute, following the rules of
+// slog.Handler: empty attributes are ignored and groups without a key are
+// inlined.
+func appendSlogAttr(fields []Field, prefix string, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	key := prefix + a.Key
+	switch v := a.Value; v.Kind() {
+	case slog.KindGroup:
+		attrs := v.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		if a.Key != "" {
+			prefix = key + "."
+		}
+		for _, ga := range attrs {
+			fields = appendSlogAttr(fields, prefix, ga)
+		}
+		return fields
+	case slog.KindString:
+		return append(fields, Str(key, v.String()))
+	case slog.KindInt64:
+		return append(fields, Int64(key, v.Int64()))
+	case slog.KindUint64:
+		return append(fields, Uint64(key, v.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, Float64(key, v.Float64()))
+	case slog.KindBool:
+		return append(fields, Bool(key, v.Bool()))
+	case slog.KindDuration:
+		return append(fields, Dur(key, v.Duration()))
+	case slog.KindTime:
+		return append(fields, Time(key, v.Time()))
+	default:
+		if err, ok := v.Any().(error); ok {
+			return append(fields, NamedErr(key, err))
+		}
+		return append(fields, Any(key, v.Any()))
+	}
+}
+
+// SlogHook is a hook routing the entries of a logger to an slog.Handler,
+// for applications whose logs are already handled by slog. Set the Out of
+// the logger to ioutil.Discard to only log through the handler.
+type SlogHook struct {
+	Handler slog.Handler
+}
+
+// NewSlogHook returns a hook routing entries to the handler.
+func NewSlogHook(handler slog.Handler) *SlogHook {
+	return &SlogHook{Handler: handler}
+}
+
+// Levels returns all the levels, the handler tells which ones it handles.
+func (hook *SlogHook) Levels() []Level {
+	return AllLevels
+}
+
+// Fire hands the entry to the handler as an slog record.
+func (hook *SlogHook) Fire(entry *Entry) error {
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	level := SlogLevel(entry.Level)
+	if !hook.Handler.Enabled(ctx, level) {
+		return nil
+	}
+
+	var pc uintptr
+	if entry.Caller != nil && entry.Caller.PC != 0 {
+		// Records hold the return address of the call, like runtime.Callers.
+		// Callers parsed from a file:line have no PC.
+		pc = entry.Caller.PC + 1
+	}
+	t := entry.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	r := slog.NewRecord(t, level, entry.Message, pc)
+
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		if fieldIndex(entry.fields, k) < 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		r.AddAttrs(slog.Any(k, entry.Data[k]))
+	}
+	for i := range entry.fields {
+		r.AddAttrs(slogAttr(entry.fields[i]))
+	}
+	return hook.Handler.Handle(ctx, r)
+}
+
+// slogAttr converts a typed field to an slog attribute.
+func slogAttr(f Field) slog.Attr {
+	switch f.kind {
+	case StringField, ByteStringField:
+		return slog.String(f.key, f.stringValue)
+	case IntField:
+		return slog.Int64(f.key, f.intValue)
+	case UintField:
+		return slog.Uint64(f.key, uint64(f.intValue))
+	case BoolField:
+		return slog.Bool(f.key, f.intValue != 0)
+	case DurationField:
+		return slog.Duration(f.key, time.Duration(f.intValue))
+	case TimeField:
+		return slog.Time(f.key, f.time())
+	default:
+		return slog.Any(f.key, f.Value())
+	}
+}
@@ -0,0 +1,132 @@
+//go:build go1.21
+// +build go1.21
+
+package hlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogHandler(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+	logger.SetLevel(TraceLevel)
+	logger.SetReportCaller(true)
+	hook := new(fieldsHook)
+	logger.AddHook(hook)
+
+	sl := slog.New(NewSlogHandler(logger)).With("service", "api").WithGroup("req")
+	sl.Info("handled", "method", "GET", slog.Group("user", "id", 7), slog.Duration("took", time.Second), slog.Any("error", errors.New("eof")), slog.Group("empty"))
+
+	var fields Fields
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &fields))
+	assert.Equal(t, "info", fields["level"])
+	assert.Equal(t, "handled", fields["msg"])
+	assert.Equal(t, "api", fields["service"])
+	assert.Equal(t, "GET", fields["req.method"])
+	assert.Equal(t, 7.0, fields["req.user.id"])
+	assert.Equal(t, "github.com/adminhmi/hlog.TestSlogHandler", fields["func"])
+	assert.Contains(t, fields["file"], "slog_test.go")
+	assert.NotContains(t, fields, "req.empty")
+	assert.Equal(t, time.Second, hook.data["req.took"])
+	assert.EqualError(t, hook.data["req.error"].(error), "eof")
+
+	buffer.Reset()
+	sl.Log(context.Background(), SlogLevelTrace, "trace")
+	assert.Contains(t, buffer.String(), `"level":"trace"`)
+
+	logger.SetLevel(InfoLevel)
+	assert.False(t, sl.Enabled(context.Background(), slog.LevelDebug))
+	assert.True(t, sl.Enabled(context.Background(), slog.LevelWarn))
+}
+
+func TestSlogHandlerContext(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &TextFormatter{DisableTimestamp: true}
+
+	ctx := NewContext(context.Background(), Fields{"request_id": "r1"})
+	slog.New(NewSlogHandler(logger)).ErrorContext(ctx, "failed")
+	assert.Equal(t, "level=error msg=failed request_id=r1\n", buffer.String())
+}
+
+func TestSlogLevels(t *testing.T) {
+	for _, level := range AllLevels {
+		assert.Equal(t, level, FromSlogLevel(SlogLevel(level)))
+	}
+	assert.Equal(t, DebugLevel, FromSlogLevel(slog.LevelDebug+1))
+	assert.Equal(t, TraceLevel, FromSlogLevel(slog.LevelDebug-1))
+}
+
+func TestSlogHook(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.AddHook(NewSlogHook(slog.NewJSONHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	logger.Info("ignored")
+	logger.WithField("user", "bob").With(Int("attempt", 2)).Warn("retrying")
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "retrying", record["msg"])
+	assert.Equal(t, "bob", record["user"])
+	assert.Equal(t, 2.0, record["attempt"])
+}
+
+// recordHandler keeps the records it handles.
+type recordHandler struct {
+	records []slog.Record
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *recordHandler) WithGroup(string) slog.Handler            { return h }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	h.records = append(h.records, r)
+	return nil
+}
+
+func TestSlogHookSource(t *testing.T) {
+	handler := new(recordHandler)
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.SetReportCaller(true)
+	hook := NewSlogHook(handler)
+
+	entry := NewEntry(logger)
+	entry.Level = InfoLevel
+	entry.Message = "parsed"
+	entry.Caller = &runtime.Frame{File: "main.go", Line: 12}
+	require.NoError(t, hook.Fire(entry))
+
+	logger.AddHook(hook)
+	logger.Info("called")
+
+	require.Len(t, handler.records, 2)
+	assert.Zero(t, handler.records[0].PC)
+	frame, _ := runtime.CallersFrames([]uintptr{handler.records[1].PC}).Next()
+	assert.Equal(t, "github.com/adminhmi/hlog.TestSlogHookSource", frame.Function)
+}
+
+func TestSlogZeroTime(t *testing.T) {
+	fields := appendSlogAttr(nil, "", slog.Time("t", time.Time{}))
+	require.Len(t, fields, 1)
+	assert.True(t, fields[0].Value().(time.Time).IsZero())
+	assert.True(t, slogAttr(Time("t", time.Time{})).Value.Time().IsZero())
+}
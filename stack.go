@@ -0,0 +1,189 @@
+package hlog
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// maximumStackDepth bounds the number of frames of a captured stack trace.
+const maximumStackDepth = 64
+
+// hlogPackage is the qualified name of this package, for trimming its
+// frames off the captured stack traces.
+var hlogPackage = getPackageName(runtime.FuncForPC(reflect.ValueOf(getPackageName).Pointer()).Name())
+
+// StackFrame is a frame of a StackTrace.
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// StackTrace is the value of the `stack` field added by Entry.WithStack and
+// by loggers with a stack level. The JSONFormatter renders it as an array of
+// frame objects and the TextFormatter as an indented block below the entry.
+type StackTrace []StackFrame
+
+// String formats the stack trace like runtime/debug.Stack, a line with the
+// function followed by a line with the indented location for each frame.
+func (st StackTrace) String() string {
+	var b bytes.Buffer
+	st.appendText(&b, "")
+	return b.String()
+}
+
+// appendText writes the frames of the stack trace, each line prefixed with
+// indent and terminated by a newline.
+func (st StackTrace) appendText(b *bytes.Buffer, indent string) {
+	for _, f := range st {
+		b.WriteString(indent)
+		b.WriteString(f.Function)
+		b.WriteByte('\n')
+		b.WriteString(indent)
+		b.WriteByte('\t')
+		b.WriteString(f.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(f.Line))
+		b.WriteByte('\n')
+	}
+}
+
+// stackTracer is implemented by the errors of github.com/pkg/errors.
+type stackTracer interface {
+	StackTrace() pkgerrors.StackTrace
+}
+
+// causer is implemented by the wrapping errors of github.com/pkg/errors.
+type causer interface {
+	Cause() error
+}
+
+// captureStack returns the stack of the calling goroutine, skipping skip
+// frames above the caller of captureStack and then any frame of this
+// package, so that entries logged from hlog internals start at user code.
+func captureStack(skip int) StackTrace {
+	pcs := make([]uintptr, maximumStackDepth)
+	n := runtime.Callers(skip+2, pcs)
+	st := framesOf(pcs[:n])
+	for len(st) > 0 && isHlogFrame(st[0]) {
+		st = st[1:]
+	}
+	return st
+}
+
+// stackOfError returns the stack trace recorded by the deepest error of the
+// chain created with github.com/pkg/errors, or nil.
+func stackOfError(err error) StackTrace {
+	var tracer stackTracer
+	for err != nil {
+		if st, ok := err.(stackTracer); ok {
+			tracer = st
+		}
+		if cause, ok := err.(causer); ok {
+			err = cause.Cause()
+		} else {
+			err = errors.Unwrap(err)
+		}
+	}
+	if tracer == nil {
+		return nil
+	}
+	frames := tracer.StackTrace()
+	pcs := make([]uintptr, len(frames))
+	for i, f := range frames {
+		// pkg/errors frames hold the return addresses like runtime.Callers
+		pcs[i] = uintptr(f)
+	}
+	return framesOf(pcs)
+}
+
+func framesOf(pcs []uintptr) StackTrace {
+	if len(pcs) == 0 {
+		return nil
+	}
+	st := make(StackTrace, 0, len(pcs))
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		st = append(st, StackFrame{Function: f.Function, File: f.File, Line: f.Line})
+		if !more {
+			break
+		}
+	}
+	return st
+}
+
+func isHlogFrame(f StackFrame) bool {
+	return getPackageName(f.Function) == hlogPackage && !strings.HasSuffix(f.File, "_test.go")
+}
+
+// WithStack adds the stack trace of the error of the entry, when it was
+// created with github.com/pkg/errors, or else the stack of the calling
+// goroutine, in the `stack` field.
+func (entry *Entry) WithStack() *Entry {
+	st := stackOfError(entry.errorValue())
+	if st == nil {
+		st = captureStack(1)
+	}
+	return entry.WithField(FieldKeyStack, st)
+}
+
+// errorValue returns the error added with WithError or Err, if any.
+func (entry *Entry) errorValue() error {
+	if i := fieldIndex(entry.fields, ErrorKey); i >= 0 {
+		err, _ := entry.fields[i].Value().(error)
+		return err
+	}
+	err, _ := entry.Data[ErrorKey].(error)
+	return err
+}
+
+// hasField reports whether the entry has a boxed or typed field named key.
+func (entry *Entry) hasField(key string) bool {
+	if _, ok := entry.Data[key]; ok {
+		return true
+	}
+	return fieldIndex(entry.fields, key) >= 0
+}
+
+// addStack adds the `stack` field to an entry being logged, unless it
+// already has one. It must only be called on entries owning their Data map.
+func (entry *Entry) addStack() {
+	if entry.hasField(FieldKeyStack) {
+		return
+	}
+	st := stackOfError(entry.errorValue())
+	if st == nil {
+		st = captureStack(1)
+	}
+	entry.Data[FieldKeyStack] = st
+}
+
+// SetStackLevel makes the logger add a stack trace to the entries logged at
+// level or at a more severe one, like WithStack does. Stack traces are
+// disabled by default.
+func (logger *Logger) SetStackLevel(level Level) {
+	logger.lock().Lock()
+	logger.stackLevel = level
+	logger.reportStack = true
+	logger.lock().Unlock()
+	logger.eachChild(func(child *Logger) {
+		child.SetStackLevel(level)
+	})
+}
+
+// DisableStack stops the stack traces enabled by SetStackLevel.
+func (logger *Logger) DisableStack() {
+	logger.lock().Lock()
+	logger.reportStack = false
+	logger.lock().Unlock()
+	logger.eachChild(func(child *Logger) {
+		child.DisableStack()
+	})
+}
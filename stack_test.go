@@ -0,0 +1,89 @@
+package hlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStackJSON(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+
+	logger.WithField("k", "v").WithStack().Info("here")
+
+	var fields struct {
+		Stack []StackFrame `json:"stack"`
+	}
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &fields))
+	require.NotEmpty(t, fields.Stack)
+	assert.Equal(t, "github.com/adminhmi/hlog.TestWithStackJSON", fields.Stack[0].Function)
+	assert.True(t, strings.HasSuffix(fields.Stack[0].File, "stack_test.go"))
+	assert.NotZero(t, fields.Stack[0].Line)
+}
+
+func TestWithStackText(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &TextFormatter{DisableTimestamp: true}
+
+	logger.WithField("k", "v").WithStack().Info("here")
+
+	lines := strings.Split(buffer.String(), "\n")
+	require.True(t, len(lines) > 3)
+	assert.Equal(t, "level=info msg=here k=v", lines[0])
+	assert.Equal(t, "stack:", lines[1])
+	assert.Equal(t, "\tgithub.com/adminhmi/hlog.TestWithStackText", lines[2])
+	assert.True(t, strings.HasPrefix(lines[3], "\t\t"), lines[3])
+	assert.Contains(t, lines[3], "stack_test.go:")
+}
+
+func TestWithStackFromError(t *testing.T) {
+	err := pkgerrors.Wrap(newStackError(), "wrapped")
+	entry := NewEntry(New()).WithError(err).WithStack()
+
+	st := entry.Data[FieldKeyStack].(StackTrace)
+	require.NotEmpty(t, st)
+	assert.Equal(t, "github.com/adminhmi/hlog.newStackError", st[0].Function)
+}
+
+func newStackError() error {
+	return pkgerrors.New("deep")
+}
+
+func TestStackLevel(t *testing.T) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	hook := new(fieldsHook)
+	logger.AddHook(hook)
+	logger.SetStackLevel(ErrorLevel)
+
+	logger.Warn("no stack")
+	assert.NotContains(t, hook.data, FieldKeyStack)
+
+	logger.Error("stack")
+	st, ok := hook.data[FieldKeyStack].(StackTrace)
+	require.True(t, ok)
+	assert.Equal(t, "github.com/adminhmi/hlog.TestStackLevel", st[0].Function)
+
+	logger.Named("child").Error("stack")
+	assert.Contains(t, hook.data, FieldKeyStack)
+
+	logger.DisableStack()
+	logger.Error("no stack")
+	assert.NotContains(t, hook.data, FieldKeyStack)
+}
+
+func TestWithStackStandardLogger(t *testing.T) {
+	st := WithStack().Data[FieldKeyStack].(StackTrace)
+	require.NotEmpty(t, st)
+	assert.Equal(t, "github.com/adminhmi/hlog.TestWithStackStandardLogger", st[0].Function)
+}
@@ -0,0 +1,114 @@
+package hlog
+
+import (
+	"log"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// stdLogPackage is the package name of the frames of the log package of
+// the standard library, skipped when looking up the caller.
+const stdLogPackage = "log"
+
+// RedirectStdLog makes the log package of the standard library log through
+// the logger at the given level, see Logger.StdLogger. It returns a function
+// restoring the previous output and flags of the log package.
+func RedirectStdLog(logger *Logger, level Level) func() {
+	out, flags := log.Writer(), log.Flags()
+	log.SetOutput(&stdLogWriter{logger: logger, level: level, flags: log.Flags, prefix: log.Prefix})
+	return func() {
+		log.SetOutput(out)
+		log.SetFlags(flags)
+	}
+}
+
+// StdLogger returns a *log.Logger whose lines are logged at the given level,
+// for libraries taking one. Each call to its Print, Fatal or Panic methods
+// becomes an entry, the Fatal and Panic ones exiting and panicking like
+// usual. At FatalLevel, each line exits through Logger.Exit, so that the exit
+// handlers run and the logger is shut down first.
+//
+// The date and time written by the Ldate, Ltime and Lmicroseconds flags are
+// stripped from the message. With the Lshortfile or Llongfile flag, the file
+// and line become the caller of the entry, reported when the logger reports
+// callers, otherwise the caller is the code calling the *log.Logger:
+//
+//	l := logger.StdLogger(hlog.WarnLevel)
+//	l.SetFlags(log.Lshortfile)
+func (logger *Logger) StdLogger(level Level) *log.Logger {
+	w := &stdLogWriter{logger: logger, level: level}
+	l := log.New(w, "", 0)
+	w.flags, w.prefix = l.Flags, l.Prefix
+	return l
+}
+
+// stdLogWriter turns the lines written by a *log.Logger into entries.
+type stdLogWriter struct {
+	logger *Logger
+	level  Level
+	// flags and prefix return the current settings of the *log.Logger
+	flags  func() int
+	prefix func() string
+}
+
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	if !w.logger.IsLevelEnabled(w.level) {
+		return len(p), nil
+	}
+	msg := strings.TrimSuffix(string(p), "\n")
+	flags := w.flags()
+	var prefix string
+	if flags&log.Lmsgprefix == 0 {
+		prefix = w.prefix()
+		msg = strings.TrimPrefix(msg, prefix)
+	}
+	msg, caller := parseStdLogHeader(msg, flags)
+
+	entry := NewEntry(w.logger)
+	entry.caller = caller
+	entry.emit(w.level, prefix+msg)
+	if w.level == FatalLevel {
+		// The log package would exit by itself, skipping the exit handlers
+		// and losing the entries still queued by the logger.
+		w.logger.Exit(1)
+	}
+	// The log package panics by itself.
+	return len(p), nil
+}
+
+// parseStdLogHeader strips the date, time and location written by the log
+// package according to its flags, the location being returned as a frame.
+func parseStdLogHeader(msg string, flags int) (string, *runtime.Frame) {
+	if flags&log.Ldate != 0 {
+		msg = skipWord(msg)
+	}
+	if flags&(log.Ltime|log.Lmicroseconds) != 0 {
+		msg = skipWord(msg)
+	}
+	if flags&(log.Lshortfile|log.Llongfile) == 0 {
+		return msg, nil
+	}
+	i := strings.Index(msg, ": ")
+	if i < 0 {
+		return msg, nil
+	}
+	location := msg[:i]
+	j := strings.LastIndexByte(location, ':')
+	if j < 0 {
+		return msg, nil
+	}
+	line, err := strconv.Atoi(location[j+1:])
+	if err != nil {
+		return msg, nil
+	}
+	return msg[i+2:], &runtime.Frame{File: location[:j], Line: line}
+}
+
+// skipWord removes the text up to the first space included.
+func skipWord(s string) string {
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
@@ -0,0 +1,104 @@
+package hlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStdLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+	logger.SetReportCaller(true)
+
+	l := logger.StdLogger(WarnLevel)
+	l.SetFlags(log.LstdFlags | log.Lmicroseconds | log.Lshortfile)
+	l.SetPrefix("[db] ")
+	l.Printf("slow query: %d ms", 250)
+
+	var fields Fields
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &fields))
+	assert.Equal(t, "warning", fields["level"])
+	assert.Equal(t, "[db] slow query: 250 ms", fields["msg"])
+	assert.Regexp(t, `^stdlog_test\.go:\d+$`, fields["file"])
+	assert.NotContains(t, fields, "func")
+
+	buffer.Reset()
+	logger.SetLevel(ErrorLevel)
+	l.Print("disabled")
+	assert.Empty(t, buffer.String())
+}
+
+func TestStdLoggerCaller(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = new(JSONFormatter)
+	logger.SetReportCaller(true)
+
+	logger.StdLogger(InfoLevel).Print("no file flag")
+	restore := RedirectStdLog(logger, InfoLevel)
+	log.Print("redirected")
+	restore()
+
+	lines := decodeLines(t, &buffer)
+	require.Len(t, lines, 2)
+	for _, fields := range lines {
+		assert.Equal(t, "github.com/adminhmi/hlog.TestStdLoggerCaller", fields["func"])
+		assert.Regexp(t, `/stdlog_test\.go:\d+$`, fields["file"])
+	}
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &TextFormatter{DisableTimestamp: true}
+
+	restore := RedirectStdLog(logger, InfoLevel)
+	log.Print("from the log package")
+	log.SetFlags(0)
+	restore()
+
+	assert.Equal(t, "level=info msg=\"from the log package\"\n", buffer.String())
+	assert.Equal(t, log.LstdFlags, log.Flags())
+	_, redirected := log.Writer().(*stdLogWriter)
+	assert.False(t, redirected)
+}
+
+func TestStdLoggerFatal(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Formatter = &TextFormatter{DisableTimestamp: true}
+	logger.SetOutput(NewAsyncWriter(&buffer, AsyncWriterOptions{}))
+	hook := &lifecycleHook{}
+	logger.AddHook(hook)
+	var code int
+	logger.ExitFunc = func(c int) {
+		code = c
+		assert.Equal(t, []string{"flush", "close"}, hook.calls)
+		assert.Equal(t, "level=fatal msg=bye\n", buffer.String())
+	}
+
+	// Print, the log package exiting by itself on Fatal
+	logger.StdLogger(FatalLevel).Print("bye")
+	assert.Equal(t, 1, code)
+}
+
+func TestParseStdLogHeader(t *testing.T) {
+	msg, caller := parseStdLogHeader("2009/01/23 01:23:23.123123 /a/b/c/d.go:23: message: details", log.LstdFlags|log.Lmicroseconds|log.Llongfile)
+	assert.Equal(t, "message: details", msg)
+	require.NotNil(t, caller)
+	assert.Equal(t, "/a/b/c/d.go", caller.File)
+	assert.Equal(t, 23, caller.Line)
+
+	msg, caller = parseStdLogHeader("no location", log.Lshortfile)
+	assert.Equal(t, "no location", msg)
+	assert.Nil(t, caller)
+}
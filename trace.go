@@ -0,0 +1,160 @@
+package hlog
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Keys of the W3C Trace Context correlation fields. They can be renamed
+// with the FieldMap of the JSONFormatter.
+const (
+	FieldKeyTraceID    = "trace_id"
+	FieldKeySpanID     = "span_id"
+	FieldKeyTraceFlags = "trace_flags"
+)
+
+// TraceparentHeader is the name of the W3C Trace Context HTTP header.
+const TraceparentHeader = "traceparent"
+
+var traceFieldKeys = [...]string{FieldKeyTraceID, FieldKeySpanID, FieldKeyTraceFlags}
+
+// TraceContext identifies the span an entry was logged in, as defined by
+// https://www.w3.org/TR/trace-context/. IDs are lowercase hex strings.
+type TraceContext struct {
+	TraceID string
+	SpanID  string
+	Flags   byte
+}
+
+// ParseTraceparent parses the value of a traceparent header, e.g.
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func ParseTraceparent(traceparent string) (TraceContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return TraceContext{}, fmt.Errorf("invalid traceparent %q", traceparent)
+	}
+	if !isHex(parts[0]) || len(parts[3]) != 2 || !isHex(parts[3]) {
+		return TraceContext{}, fmt.Errorf("invalid traceparent %q", traceparent)
+	}
+	flags, _ := strconv.ParseUint(parts[3], 16, 8)
+	tc := TraceContext{TraceID: parts[1], SpanID: parts[2], Flags: byte(flags)}
+	if !tc.IsValid() {
+		return TraceContext{}, fmt.Errorf("invalid traceparent %q", traceparent)
+	}
+	return tc, nil
+}
+
+// IsValid reports whether the trace and span IDs are well formed and not
+// all zeros.
+func (tc TraceContext) IsValid() bool {
+	return len(tc.TraceID) == 32 && isHex(tc.TraceID) && strings.Trim(tc.TraceID, "0") != "" &&
+		len(tc.SpanID) == 16 && isHex(tc.SpanID) && strings.Trim(tc.SpanID, "0") != ""
+}
+
+// Sampled reports whether the sampled flag is set.
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&0x01 != 0
+}
+
+// Traceparent returns the traceparent header value of the trace context.
+func (tc TraceContext) Traceparent() string {
+	return "00-" + tc.TraceID + "-" + tc.SpanID + "-" + tc.flags()
+}
+
+func (tc TraceContext) flags() string {
+	return hex.EncodeToString([]byte{tc.Flags})
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+type traceContextKey struct{}
+
+// ContextWithTrace returns a copy of ctx carrying the trace context. Entries
+// logged with the context get the trace_id, span_id and trace_flags fields.
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// ContextWithTraceparent parses a traceparent header value and returns a copy
+// of ctx carrying it, see ContextWithTrace.
+func ContextWithTraceparent(ctx context.Context, traceparent string) (context.Context, error) {
+	tc, err := ParseTraceparent(traceparent)
+	if err != nil {
+		return ctx, err
+	}
+	return ContextWithTrace(ctx, tc), nil
+}
+
+// TraceFromContext returns the trace context carried by ctx.
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	if ctx == nil {
+		return TraceContext{}, false
+	}
+	tc, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc, ok
+}
+
+// TraceFromFields returns the trace context held by the trace_id, span_id
+// and trace_flags fields, as found in the Data of the entries given to
+// hooks.
+func TraceFromFields(fields Fields) (TraceContext, bool) {
+	traceID, _ := fields[FieldKeyTraceID].(string)
+	spanID, _ := fields[FieldKeySpanID].(string)
+	tc := TraceContext{TraceID: traceID, SpanID: spanID}
+	if flags, ok := fields[FieldKeyTraceFlags].(string); ok {
+		if b, err := strconv.ParseUint(flags, 16, 8); err == nil {
+			tc.Flags = byte(b)
+		}
+	}
+	return tc, tc.IsValid()
+}
+
+// mergeTrace adds the fields of the trace context carried by the entry
+// context, unless the entry already has a trace_id.
+func (entry *Entry) mergeTrace() {
+	tc, ok := TraceFromContext(entry.Context)
+	if !ok {
+		return
+	}
+	if _, ok := entry.Data[FieldKeyTraceID]; ok || fieldIndex(entry.fields, FieldKeyTraceID) >= 0 {
+		return
+	}
+	fields := make([]Field, len(entry.fields), len(entry.fields)+len(traceFieldKeys))
+	copy(fields, entry.fields)
+	entry.fields = append(fields,
+		Str(FieldKeyTraceID, tc.TraceID),
+		Str(FieldKeySpanID, tc.SpanID),
+		Str(FieldKeyTraceFlags, tc.flags()),
+	)
+}
+
+// takeTraceFields moves the trace fields from the boxed and typed fields to
+// trace, renamed with the field map. The slices are modified in place.
+func takeTraceFields(data, fields, trace []Field, fieldMap FieldMap) ([]Field, []Field, []Field) {
+	for _, key := range traceFieldKeys {
+		var f Field
+		if i := fieldIndex(fields, key); i >= 0 {
+			f = fields[i]
+			fields = append(fields[:i], fields[i+1:]...)
+		} else if i := fieldIndex(data, key); i >= 0 {
+			f = data[i]
+			data = append(data[:i], data[i+1:]...)
+		} else {
+			continue
+		}
+		f.key = fieldMap.resolve(fieldKey(key))
+		trace = append(trace, f)
+	}
+	return data, fields, trace
+}
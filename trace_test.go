@@ -0,0 +1,78 @@
+package hlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceparent(t *testing.T) {
+	tc, err := ParseTraceparent(testTraceparent)
+	require.NoError(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", tc.SpanID)
+	assert.True(t, tc.Sampled())
+	assert.Equal(t, testTraceparent, tc.Traceparent())
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		_, err := ParseTraceparent(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestTraceFields(t *testing.T) {
+	ctx, err := ContextWithTraceparent(context.Background(), testTraceparent)
+	require.NoError(t, err)
+
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.WithContext(ctx).Info("traced")
+	}, func(fields Fields) {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", fields[FieldKeyTraceID])
+		assert.Equal(t, "00f067aa0ba902b7", fields[FieldKeySpanID])
+		assert.Equal(t, "01", fields[FieldKeyTraceFlags])
+	})
+
+	hook := new(fieldsHook)
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.AddHook(hook)
+	logger.WithContext(ctx).Info("traced")
+	tc, ok := TraceFromFields(hook.data)
+	assert.True(t, ok)
+	assert.Equal(t, testTraceparent, tc.Traceparent())
+}
+
+func TestTraceFieldMap(t *testing.T) {
+	ctx, err := ContextWithTraceparent(context.Background(), testTraceparent)
+	require.NoError(t, err)
+
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &JSONFormatter{
+		DataKey:  "data",
+		FieldMap: FieldMap{FieldKeyTraceID: "trace.id", FieldKeySpanID: "span.id"},
+	}
+	logger.WithContext(ctx).WithField("k", "v").Info("traced")
+
+	var fields Fields
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &fields))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", fields["trace.id"])
+	assert.Equal(t, "00f067aa0ba902b7", fields["span.id"])
+	assert.Equal(t, "01", fields[FieldKeyTraceFlags])
+	assert.Equal(t, map[string]interface{}{"k": "v"}, fields["data"])
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"io"
 	"runtime"
+	"strings"
 )
 
 // Writer at INFO level. See WriterLevel for details.
@@ -55,12 +56,20 @@ func (entry *Entry) WriterLevel(level Level) *io.PipeWriter {
 }
 
 func (entry *Entry) writerScanner(reader *io.PipeReader, printFunc func(args ...interface{})) {
-	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
-		printFunc(scanner.Text())
-	}
-	if err := scanner.Err(); err != nil {
-		entry.Errorf("Error while reading from Writer: %s", err)
+	// A bufio.Reader rather than a bufio.Scanner, lines have no length limit.
+	br := bufio.NewReader(reader)
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			line = strings.TrimSuffix(line, "\n")
+			printFunc(strings.TrimSuffix(line, "\r"))
+		}
+		if err != nil {
+			if err != io.EOF {
+				entry.Errorf("Error while reading from Writer: %s", err)
+			}
+			break
+		}
 	}
 	reader.Close()
 }